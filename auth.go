@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"github.com/rs/zerolog"
 	"io"
@@ -12,28 +13,58 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Authorizer interface {
 	SetAuthHeader(req *http.Request)
 }
 
-type anonAuthInfo struct {
-	BearerToken string
-	GuestID     string
-	GuestToken  string
-	CSRFToken   string
+// ResponseObserver can be implemented by an Authorizer to get feedback
+// about its credentials. Client calls ObserveResponse after every request,
+// before the response body is read, so that the authorizer can look at the
+// status code, headers and cookies. If a request fails with 401 or 403,
+// Client retries it once, giving the authorizer a chance to update its
+// credentials.
+type ResponseObserver interface {
+	ObserveResponse(resp *http.Response)
 }
 
-func getAnonAuthInfo(ctx context.Context) (*anonAuthInfo, error) {
-	log := zerolog.Ctx(ctx)
+// tokenInvalidator is implemented by authorizers that can be told that the
+// credentials used for a request were rejected, in cases that
+// ObserveResponse can't detect by itself, like error codes in the body of a
+// successful response.
+type tokenInvalidator interface {
+	invalidateToken(req *http.Request)
+}
 
-	r := &anonAuthInfo{}
-	jar, err := cookiejar.New(nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating a cookie jar: %w", err)
+type httpClientKey struct{}
+
+// withHTTPClient makes the HTTP client available to authorizers, so that
+// they use the same client as Client when they need to refresh credentials.
+func withHTTPClient(ctx context.Context, client *http.Client) context.Context {
+	return context.WithValue(ctx, httpClientKey{}, client)
+}
+
+func httpClientFromContext(ctx context.Context) *http.Client {
+	if c, ok := ctx.Value(httpClientKey{}).(*http.Client); ok && c != nil {
+		return c
 	}
-	client := &http.Client{Jar: jar}
+	return http.DefaultClient
+}
+
+type anonAuthInfo struct {
+	BearerToken string    `json:"bearer_token"`
+	GuestID     string    `json:"guest_id"`
+	GuestToken  string    `json:"guest_token"`
+	CSRFToken   string    `json:"csrf_token"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// fetchHomePage fetches the HTML of twitter.com.
+func fetchHomePage(ctx context.Context, client *http.Client) ([]byte, error) {
+	log := zerolog.Ctx(ctx)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", "https://twitter.com", nil)
 	if err != nil {
@@ -52,11 +83,56 @@ func getAnonAuthInfo(ctx context.Context) (*anonAuthInfo, error) {
 	if err != nil {
 		return nil, fmt.Errorf("reading html response: %w", err)
 	}
-	matches := regexp.MustCompile(`https://[^"]+/main.[^.]+.js`).FindAll(b, 1)
+	return b, nil
+}
+
+// fetchMainJS fetches the main js file referenced by the HTML page.
+func fetchMainJS(ctx context.Context, client *http.Client, html []byte) ([]byte, error) {
+	matches := regexp.MustCompile(`https://[^"]+/main.[^.]+.js`).FindAll(html, 1)
 	if len(matches) < 1 {
 		return nil, fmt.Errorf("didn't find a URL of the main js file")
 	}
 
+	req, err := http.NewRequestWithContext(ctx, "GET", string(matches[0]), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request object: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching js: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("server returned unexpected response: %s\n%+v", resp.Status, resp)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading js response: %w", err)
+	}
+	return b, nil
+}
+
+func findBearerToken(js []byte) (string, error) {
+	matches := regexp.MustCompile(`AAAAAAAAA[^"]+`).FindAll(js, 1)
+	if len(matches) < 1 {
+		return "", fmt.Errorf("didn't find the token in main js file")
+	}
+	return string(matches[0]), nil
+}
+
+func getAnonAuthInfo(ctx context.Context) (*anonAuthInfo, error) {
+	r := &anonAuthInfo{}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating a cookie jar: %w", err)
+	}
+	client := &http.Client{Jar: jar, Transport: httpClientFromContext(ctx).Transport}
+
+	html, err := fetchHomePage(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
 	u, _ := url.Parse("https://twitter.com")
 	for _, c := range jar.Cookies(u) {
 		switch c.Name {
@@ -67,7 +143,7 @@ func getAnonAuthInfo(ctx context.Context) (*anonAuthInfo, error) {
 		}
 	}
 	if r.GuestToken == "" {
-		matches := regexp.MustCompile(`document.cookie="gt=([^;"]+);`).FindAllSubmatch(b, 1)
+		matches := regexp.MustCompile(`document.cookie="gt=([^;"]+);`).FindAllSubmatch(html, 1)
 		if len(matches) >= 1 {
 			r.GuestToken = string(matches[0][1])
 		}
@@ -77,43 +153,79 @@ func getAnonAuthInfo(ctx context.Context) (*anonAuthInfo, error) {
 		return nil, fmt.Errorf("guest_id/gt must be not empty (%q/%q)", r.GuestID, r.GuestToken)
 	}
 
-	req, err = http.NewRequestWithContext(ctx, "GET", string(matches[0]), nil)
+	js, err := fetchMainJS(ctx, client, html)
 	if err != nil {
-		return nil, fmt.Errorf("creating request object: %w", err)
-	}
-	resp, err = client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("fetching js: %w", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("server returned unexpected response: %s\n%+v", resp.Status, resp)
+		return nil, err
 	}
-	b, err = io.ReadAll(resp.Body)
+	registry.update(ctx, html, js)
+	r.BearerToken, err = findBearerToken(js)
 	if err != nil {
-		return nil, fmt.Errorf("reading js response: %w", err)
-	}
-	matches = regexp.MustCompile(`AAAAAAAAA[^"]+`).FindAll(b, 1)
-	if len(matches) < 1 {
-		return nil, fmt.Errorf("didn't find the token in main js file")
+		return nil, err
 	}
 
-	r.BearerToken = string(matches[0])
-
-	b = make([]byte, 16)
+	b := make([]byte, 16)
 	if _, err := rand.Read(b); err != nil {
 		return nil, fmt.Errorf("failed to generate random bytes: %w", err)
 	}
 	r.CSRFToken = hex.EncodeToString(b)
+	r.CreatedAt = time.Now()
 
 	return r, nil
 }
 
-type AnonymousAuthorizer struct {
-	info anonAuthInfo
+const (
+	guestActivateURL = "https://api.twitter.com/1.1/guest/activate.json"
+
+	// DefaultGuestTokenMaxRequests is the number of requests after which
+	// AnonymousAuthorizer gets a new guest token, unless configured otherwise.
+	DefaultGuestTokenMaxRequests = 150
+)
+
+// activateGuestToken requests a new guest token using the bearer token.
+func activateGuestToken(ctx context.Context, bearerToken string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", guestActivateURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request object: %w", err)
+	}
+	req.Header.Set("authorization", fmt.Sprintf("Bearer %s", bearerToken))
+
+	resp, err := httpClientFromContext(ctx).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("sending HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := errorFromResponse(resp); err != nil {
+		return "", err
+	}
+
+	r := struct {
+		GuestToken string `json:"guest_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return "", fmt.Errorf("unmarshaling JSON response: %w", err)
+	}
+	if r.GuestToken == "" {
+		return "", fmt.Errorf("response doesn't contain a guest token")
+	}
+	return r.GuestToken, nil
 }
 
-// TODO: MarshalJSON/UnmarshalJSON
+// AnonymousAuthorizer authorizes requests as a logged out user. It gets a
+// new guest token when the current one is rejected by the server or after
+// MaxRequests requests. It is safe for concurrent use.
+type AnonymousAuthorizer struct {
+	// MaxRequests is the number of requests to make with a single guest
+	// token. Zero means DefaultGuestTokenMaxRequests.
+	MaxRequests int
+
+	mu       sync.Mutex
+	info     anonAuthInfo
+	requests int
+	stale    bool
+	// refreshDone is set while new credentials are being fetched, and is
+	// closed when that's done.
+	refreshDone chan struct{}
+}
 
 func AnonymousAuth(ctx context.Context) (*AnonymousAuthorizer, error) {
 	i, err := getAnonAuthInfo(ctx)
@@ -123,19 +235,148 @@ func AnonymousAuth(ctx context.Context) (*AnonymousAuthorizer, error) {
 	return &AnonymousAuthorizer{info: *i}, nil
 }
 
+// Invalidate makes the authorizer get a new guest token before the next
+// request.
+func (a *AnonymousAuthorizer) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stale = true
+}
+
+// ObserveResponse marks the guest token as stale if the server rejected it.
+func (a *AnonymousAuthorizer) ObserveResponse(resp *http.Response) {
+	if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+		return
+	}
+	a.invalidateToken(resp.Request)
+}
+
+// invalidateToken marks the guest token used by req as stale.
+func (a *AnonymousAuthorizer) invalidateToken(req *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// Several requests might have failed with the same token, but we need
+	// to replace it only once.
+	if req != nil && req.Header.Get("x-guest-token") != a.info.GuestToken {
+		return
+	}
+	a.stale = true
+}
+
+// credentials returns the credentials to use for the next request,
+// refreshing them first if needed. Only one goroutine fetches new
+// credentials at a time, while others wait for it without holding a.mu.
+func (a *AnonymousAuthorizer) credentials(ctx context.Context) anonAuthInfo {
+	log := zerolog.Ctx(ctx)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for {
+		max := a.MaxRequests
+		if max <= 0 {
+			max = DefaultGuestTokenMaxRequests
+		}
+		if !a.stale && a.requests < max {
+			break
+		}
+		if done := a.refreshDone; done != nil {
+			a.mu.Unlock()
+			select {
+			case <-done:
+			case <-ctx.Done():
+			}
+			a.mu.Lock()
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+
+		done := make(chan struct{})
+		a.refreshDone = done
+		old := a.info
+		a.mu.Unlock()
+		info, err := refreshAnonAuthInfo(ctx, old)
+		a.mu.Lock()
+		a.refreshDone = nil
+		close(done)
+		if err != nil {
+			// Keep the token marked as stale, so that the next
+			// request tries again.
+			log.Error().Err(err).Msgf("Failed to refresh credentials, keeping the old ones: %s", err)
+			break
+		}
+		a.info = *info
+		a.stale = false
+		a.requests = 0
+	}
+	a.requests++
+	return a.info
+}
+
+// refreshAnonAuthInfo gets a new guest token. If activating a new one
+// doesn't work, it starts from scratch, as if the authorizer was just
+// created.
+func refreshAnonAuthInfo(ctx context.Context, old anonAuthInfo) (*anonAuthInfo, error) {
+	log := zerolog.Ctx(ctx)
+
+	token, err := activateGuestToken(ctx, old.BearerToken)
+	if err == nil {
+		log.Debug().Msgf("Activated a new guest token")
+		old.GuestToken = token
+		old.CreatedAt = time.Now()
+		return &old, nil
+	}
+	log.Info().Err(err).Msgf("Failed to activate a new guest token, fetching new credentials: %s", err)
+	return getAnonAuthInfo(ctx)
+}
+
+// CreatedAt returns the time when the current credentials were obtained.
+func (a *AnonymousAuthorizer) CreatedAt() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.info.CreatedAt
+}
+
+// MarshalJSON serializes the credentials, so that they can be saved and
+// reused later with UnmarshalJSON instead of calling AnonymousAuth again.
+func (a *AnonymousAuthorizer) MarshalJSON() ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return json.Marshal(a.info)
+}
+
+func (a *AnonymousAuthorizer) UnmarshalJSON(b []byte) error {
+	info := anonAuthInfo{}
+	if err := json.Unmarshal(b, &info); err != nil {
+		return err
+	}
+	if info.BearerToken == "" || info.GuestToken == "" {
+		return fmt.Errorf("bearer_token and guest_token must be not empty")
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.info = info
+	a.requests = 0
+	a.stale = false
+	return nil
+}
+
 func (a *AnonymousAuthorizer) SetAuthHeader(req *http.Request) {
+	info := a.credentials(req.Context())
+
 	cookie := func(name string, value string) string {
 		return (&http.Cookie{Name: name, Value: value}).String()
 	}
 	cookies := []string{
-		cookie("guest_id", a.info.GuestID),
-		cookie("gt", a.info.GuestToken),
-		cookie("ct0", a.info.CSRFToken),
+		cookie("guest_id", info.GuestID),
+		cookie("gt", info.GuestToken),
+		cookie("ct0", info.CSRFToken),
 		cookie("dnt", "1"),
 	}
 	req.Header.Set("Cookie", strings.Join(cookies, "; "))
-	req.Header.Set("authorization", fmt.Sprintf("Bearer %s", a.info.BearerToken))
-	req.Header.Set("x-csrf-token", a.info.CSRFToken)
-	req.Header.Set("x-guest-token", a.info.GuestToken)
+	req.Header.Set("authorization", fmt.Sprintf("Bearer %s", info.BearerToken))
+	req.Header.Set("x-csrf-token", info.CSRFToken)
+	req.Header.Set("x-guest-token", info.GuestToken)
 	req.Header.Set("DNT", "1")
 }
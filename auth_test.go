@@ -0,0 +1,222 @@
+package pwitter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAnonymousAuthorizerJSON(t *testing.T) {
+	info := anonAuthInfo{
+		BearerToken: "AAAAAAAAAbearer",
+		GuestID:     "v1%3A123",
+		GuestToken:  "456",
+		CSRFToken:   "0123456789abcdef",
+		CreatedAt:   time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC),
+	}
+	b, err := json.Marshal(&AnonymousAuthorizer{info: info})
+	if err != nil {
+		t.Fatalf("Marshal returned error: %s", err)
+	}
+
+	got := &AnonymousAuthorizer{}
+	if err := json.Unmarshal(b, got); err != nil {
+		t.Fatalf("Unmarshal returned error: %s", err)
+	}
+	if got.info != info {
+		t.Errorf("got %+v, want %+v", got.info, info)
+	}
+	if !got.CreatedAt().Equal(info.CreatedAt) {
+		t.Errorf("CreatedAt() = %s, want %s", got.CreatedAt(), info.CreatedAt)
+	}
+
+	if err := json.Unmarshal([]byte(`{"guest_id":"v1%3A123"}`), &AnonymousAuthorizer{}); err == nil {
+		t.Errorf("Unmarshal accepted credentials without tokens")
+	}
+}
+
+func TestPoolAuthorizerJSON(t *testing.T) {
+	p := testPool("a", "b")
+	p.Strategy = LeastRecentlyThrottled
+	for _, m := range p.members {
+		m.auth.info.BearerToken = "AAAAAAAAAbearer"
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal returned error: %s", err)
+	}
+
+	got := &PoolAuthorizer{}
+	if err := json.Unmarshal(b, got); err != nil {
+		t.Fatalf("Unmarshal returned error: %s", err)
+	}
+	if got.Strategy != p.Strategy {
+		t.Errorf("got strategy %d, want %d", got.Strategy, p.Strategy)
+	}
+	if len(got.members) != 2 || got.members[0].auth.info.GuestToken != "a" || got.members[1].auth.info.GuestToken != "b" {
+		t.Errorf("identities were not restored: %s", b)
+	}
+}
+
+type recordingAuthorizer struct {
+	mu       sync.Mutex
+	statuses []int
+}
+
+func (a *recordingAuthorizer) SetAuthHeader(req *http.Request) {}
+
+func (a *recordingAuthorizer) ObserveResponse(resp *http.Response) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.statuses = append(a.statuses, resp.StatusCode)
+}
+
+func TestClientCallsObserveResponse(t *testing.T) {
+	client, count := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		if n == 1 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, testUserResponse)
+	})
+	auth := &recordingAuthorizer{}
+	client.Authorizer = auth
+
+	if _, err := client.UserByScreenName(context.Background(), "test"); err != nil {
+		t.Fatalf("UserByScreenName returned error: %s", err)
+	}
+	if *count != 2 {
+		t.Errorf("expected 2 requests, got %d", *count)
+	}
+	if diff := cmp.Diff([]int{http.StatusForbidden, http.StatusOK}, auth.statuses); diff != "" {
+		t.Errorf("unexpected observed responses (-want +got):\n%s", diff)
+	}
+}
+
+func testAnonymousAuthorizer(token string) *AnonymousAuthorizer {
+	return &AnonymousAuthorizer{info: anonAuthInfo{
+		BearerToken: "AAAAAAAAAbearer",
+		GuestID:     "v1%3A123",
+		GuestToken:  token,
+		CSRFToken:   "0123456789abcdef",
+	}}
+}
+
+func TestAnonymousAuthorizerRefresh(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		rejected func(w http.ResponseWriter)
+	}{
+		{
+			name:     "forbidden",
+			rejected: func(w http.ResponseWriter) { w.WriteHeader(http.StatusForbidden) },
+		},
+		{
+			name: "bad guest token",
+			rejected: func(w http.ResponseWriter) {
+				fmt.Fprint(w, `{"errors":[{"code":239,"message":"Bad guest token"}]}`)
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client, count := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/1.1/guest/activate.json" {
+					if got := r.Header.Get("authorization"); got != "Bearer AAAAAAAAAbearer" {
+						t.Errorf("activation request has authorization %q", got)
+					}
+					fmt.Fprint(w, `{"guest_token":"new"}`)
+					return
+				}
+				if r.Header.Get("x-guest-token") == "old" {
+					tc.rejected(w)
+					return
+				}
+				fmt.Fprint(w, testUserResponse)
+			})
+			auth := testAnonymousAuthorizer("old")
+			client.Authorizer = auth
+
+			if _, err := client.UserByScreenName(context.Background(), "test"); err != nil {
+				t.Fatalf("UserByScreenName returned error: %s", err)
+			}
+			if *count != 3 {
+				t.Errorf("expected 3 requests, got %d", *count)
+			}
+			if auth.info.GuestToken != "new" || auth.stale {
+				t.Errorf("got token %q (stale: %v), want a fresh %q", auth.info.GuestToken, auth.stale, "new")
+			}
+		})
+	}
+}
+
+func TestAnonymousAuthorizerRefreshFailure(t *testing.T) {
+	client, count := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	ctx := withHTTPClient(context.Background(), client.Client)
+	auth := testAnonymousAuthorizer("old")
+	auth.Invalidate()
+
+	for i := 1; i <= 2; i++ {
+		if got := auth.credentials(ctx); got.GuestToken != "old" {
+			t.Errorf("got token %q, want the old one", got.GuestToken)
+		}
+		if !auth.stale {
+			t.Errorf("token is not stale after a failed refresh")
+		}
+		// Each refresh tries to activate a token, then to fetch the home
+		// page.
+		if *count != int32(2*i) {
+			t.Errorf("expected %d requests, got %d", 2*i, *count)
+		}
+	}
+}
+
+func TestAnonymousAuthorizerConcurrentRefresh(t *testing.T) {
+	release := make(chan struct{})
+	client, count := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprintf(w, `{"guest_token":"new%d"}`, n)
+	})
+	ctx := withHTTPClient(context.Background(), client.Client)
+	auth := testAnonymousAuthorizer("old")
+	auth.Invalidate()
+
+	const callers = 10
+	tokens := make(chan string, callers)
+	wg := sync.WaitGroup{}
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tokens <- auth.credentials(ctx).GuestToken
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	// Other callers must not be blocked on the lock while the token is
+	// being refreshed.
+	if got := auth.CreatedAt(); !got.IsZero() {
+		t.Errorf("CreatedAt() = %s before refresh is done", got)
+	}
+	close(release)
+	wg.Wait()
+	close(tokens)
+
+	if *count != 1 {
+		t.Errorf("expected 1 activation request, got %d", *count)
+	}
+	for token := range tokens {
+		if token != "new1" {
+			t.Errorf("got token %q, want %q", token, "new1")
+		}
+	}
+	if auth.requests != callers {
+		t.Errorf("got %d requests counted, want %d", auth.requests, callers)
+	}
+}
@@ -0,0 +1,60 @@
+package pwitter
+
+// CommunityNote is a Community Note (formerly Birdwatch) shown under a tweet.
+type CommunityNote struct {
+	ID string `json:"id"`
+	// Title is the heading shown above the note, e.g. "Readers added
+	// context they thought people might want to know".
+	Title string `json:"title,omitempty"`
+	Text  string `json:"text"`
+	// RatingStatus is e.g. "CurrentlyRatedHelpful". It is empty if the
+	// response doesn't say.
+	RatingStatus string `json:"rating_status,omitempty"`
+	// Sources are the URLs referenced by the note.
+	Sources []string `json:"sources,omitempty"`
+	URL     string   `json:"url,omitempty"`
+}
+
+type graphqlBirdwatchPivot struct {
+	Title          string `json:"title"`
+	ShortTitle     string `json:"shorttitle"`
+	DestinationURL string `json:"destinationUrl"`
+	Note           struct {
+		RestID       string `json:"rest_id"`
+		RatingStatus string `json:"rating_status,omitempty"`
+	} `json:"note"`
+	Subtitle struct {
+		Text     string `json:"text"`
+		Entities []struct {
+			FromIndex int `json:"fromIndex"`
+			ToIndex   int `json:"toIndex"`
+			Ref       struct {
+				Type    string `json:"type"`
+				URL     string `json:"url"`
+				URLType string `json:"urlType"`
+			} `json:"ref"`
+		} `json:"entities"`
+	} `json:"subtitle"`
+}
+
+func (p *graphqlBirdwatchPivot) CommunityNote() *CommunityNote {
+	if p.Note.RestID == "" && p.Subtitle.Text == "" {
+		return nil
+	}
+	r := &CommunityNote{
+		ID:           p.Note.RestID,
+		Title:        p.Title,
+		Text:         p.Subtitle.Text,
+		RatingStatus: p.Note.RatingStatus,
+		URL:          p.DestinationURL,
+	}
+	seen := map[string]bool{}
+	for _, e := range p.Subtitle.Entities {
+		if e.Ref.URL == "" || seen[e.Ref.URL] {
+			continue
+		}
+		r.Sources = append(r.Sources, e.Ref.URL)
+		seen[e.Ref.URL] = true
+	}
+	return r
+}
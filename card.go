@@ -0,0 +1,151 @@
+package pwitter
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Poll is a poll attached to a tweet, in the format of API v2 poll object.
+type Poll struct {
+	ID              string       `json:"id"`
+	Options         []PollOption `json:"options"`
+	DurationMinutes int          `json:"duration_minutes,omitempty"`
+	EndDatetime     string       `json:"end_datetime,omitempty"`
+	// VotingStatus is either "open" or "closed".
+	VotingStatus string `json:"voting_status,omitempty"`
+}
+
+type PollOption struct {
+	Position int    `json:"position"`
+	Label    string `json:"label"`
+	Votes    int    `json:"votes"`
+}
+
+// Card is a link preview attached to a tweet.
+type Card struct {
+	// Name is the kind of the card, e.g. "summary" or "summary_large_image".
+	Name         string `json:"name"`
+	URL          string `json:"url,omitempty"`
+	Title        string `json:"title,omitempty"`
+	Description  string `json:"description,omitempty"`
+	Domain       string `json:"domain,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+}
+
+type graphqlCard struct {
+	RestID string `json:"rest_id"`
+	Legacy struct {
+		Name          string `json:"name"`
+		URL           string `json:"url"`
+		BindingValues []struct {
+			Key   string `json:"key"`
+			Value struct {
+				Type         string `json:"type"`
+				StringValue  string `json:"string_value,omitempty"`
+				BooleanValue bool   `json:"boolean_value,omitempty"`
+				ImageValue   *struct {
+					URL    string `json:"url"`
+					Width  int    `json:"width"`
+					Height int    `json:"height"`
+				} `json:"image_value,omitempty"`
+			} `json:"value"`
+		} `json:"binding_values"`
+	} `json:"legacy"`
+}
+
+func (c *graphqlCard) stringValues() map[string]string {
+	r := map[string]string{}
+	for _, b := range c.Legacy.BindingValues {
+		switch {
+		case b.Value.ImageValue != nil:
+			r[b.Key] = b.Value.ImageValue.URL
+		case b.Value.Type == "BOOLEAN":
+			r[b.Key] = strconv.FormatBool(b.Value.BooleanValue)
+		default:
+			r[b.Key] = b.Value.StringValue
+		}
+	}
+	return r
+}
+
+// isPoll reports whether the card is a poll. Polls have names like
+// "poll2choice_text_only" or "poll4choice_image".
+func (c *graphqlCard) isPoll() bool {
+	return strings.HasPrefix(c.Legacy.Name, "poll") && strings.Contains(c.Legacy.Name, "choice")
+}
+
+// Poll converts a poll card. Voting status is derived from the card itself
+// where possible, so that the result doesn't depend on when it's converted.
+func (c *graphqlCard) Poll() *Poll {
+	if !c.isPoll() {
+		return nil
+	}
+	v := c.stringValues()
+	r := &Poll{
+		// Card URLs look like "card://1650000000000000000".
+		ID:          strings.TrimPrefix(c.Legacy.URL, "card://"),
+		EndDatetime: v["end_datetime_utc"],
+	}
+	if r.ID == "" {
+		r.ID = strings.TrimPrefix(c.RestID, "card://")
+	}
+	r.DurationMinutes, _ = strconv.Atoi(v["duration_minutes"])
+	for i := 1; ; i++ {
+		label, ok := v["choice"+strconv.Itoa(i)+"_label"]
+		if !ok {
+			break
+		}
+		votes, _ := strconv.Atoi(v["choice"+strconv.Itoa(i)+"_count"])
+		r.Options = append(r.Options, PollOption{Position: i, Label: label, Votes: votes})
+	}
+
+	// The card is a snapshot taken at last_updated_datetime_utc, so that's
+	// what we compare the end of the poll with. Without it, we can only
+	// compare with the current time.
+	r.VotingStatus = "open"
+	if v["counts_are_final"] == "true" {
+		r.VotingStatus = "closed"
+	} else if end, err := time.Parse(time.RFC3339, r.EndDatetime); err == nil {
+		updated, err := time.Parse(time.RFC3339, v["last_updated_datetime_utc"])
+		if err != nil {
+			updated = time.Now()
+		}
+		if !updated.Before(end) {
+			r.VotingStatus = "closed"
+		}
+	}
+	return r
+}
+
+// Card converts a link card. It returns nil for polls and cards that don't
+// link anywhere.
+func (c *graphqlCard) Card() *Card {
+	if c.isPoll() {
+		return nil
+	}
+	v := c.stringValues()
+	r := &Card{
+		Name:        c.Legacy.Name,
+		URL:         v["card_url"],
+		Title:       v["title"],
+		Description: v["description"],
+		Domain:      v["domain"],
+	}
+	if r.URL == "" {
+		r.URL = c.Legacy.URL
+	}
+	if r.Domain == "" {
+		r.Domain = v["vanity_url"]
+	}
+	for _, k := range []string{"thumbnail_image_original", "thumbnail_image_large", "thumbnail_image", "summary_photo_image_original", "summary_photo_image", "photo_image_full_size_original", "photo_image_full_size"} {
+		if v[k] != "" {
+			r.ThumbnailURL = v[k]
+			break
+		}
+	}
+	if r.URL == "" && r.Title == "" {
+		return nil
+	}
+	return r
+}
@@ -3,46 +3,176 @@ package pwitter
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/Ukraine-DAO/twitter-threads/twitter"
 	"github.com/rs/zerolog"
 )
 
+// Client makes requests to the GraphQL API used by the web app. Query IDs
+// and feature switches discovered at runtime are shared by all Clients in
+// the process, since they depend only on the deployed version of the web
+// app.
 type Client struct {
 	Authorizer Authorizer
 	Client     *http.Client
+	// Retry controls retrying of failed requests. Requests are not retried
+	// if it is nil.
+	Retry *RetryPolicy
+	// WaitForRateLimit makes the Client wait for the rate limit reset
+	// instead of sending a request that would be throttled.
+	WaitForRateLimit bool
+	// UnescapeHTML replaces HTML entities like "&amp;" in tweet text with
+	// the characters they stand for, and adjusts entity offsets to match.
+	UnescapeHTML bool
+
+	rateLimits rateLimits
 }
 
 type UserTweetsResponse struct {
-	RawJSON    []byte
-	Tweets     []twitter.Tweet
-	CursorNext string
-	CursorPrev string
+	RawJSON []byte
+	Tweets  []twitter.Tweet
+	// ExtendedTweets are the same tweets as Tweets, with the data that
+	// twitter.Tweet has no fields for. Unlike Tweets, they also include
+	// placeholders for unavailable tweets, see TweetNoIncludes.Tombstone.
+	ExtendedTweets []Tweet
+	CursorNext     string
+	CursorPrev     string
 }
 
 func (c *Client) UserTweets(ctx context.Context, userID string, cursor string) (*UserTweetsResponse, error) {
-	if c.Client == nil {
-		c.Client = http.DefaultClient
+	return c.userTimeline(ctx, "UserTweets", userID, cursor)
+}
+
+// graphQL executes a GraphQL query and decodes the response into out.
+// All Client methods must go through it, so that anything that needs to
+// happen on every request is added here.
+func (c *Client) graphQL(ctx context.Context, queryName string, vars string, out interface{}) error {
+	if err := c.doGraphQL(ctx, queryName, vars, out); err != nil {
+		return fmt.Errorf("%s: %w", queryName, err)
 	}
+	return nil
+}
 
-	log := zerolog.Ctx(ctx).With().
-		Str("user_id", userID).
-		Str("method", "UserTweets").Logger()
-	ctx = log.WithContext(ctx)
+func (c *Client) doGraphQL(ctx context.Context, queryName string, vars string, out interface{}) error {
+	r, err := c.send(ctx, queryName, vars)
+	if err != nil {
+		return err
+	}
+
+	if len(r.Errors) > 0 {
+		zerolog.Ctx(ctx).Debug().Msgf("Response contains errors: %s", r.Errors)
+		if isEmptyData(r.Data) {
+			return r.Errors.Err()
+		}
+	}
+
+	if err := json.Unmarshal(r.Body, out); err != nil {
+		return fmt.Errorf("unmarshaling JSON response: %w", err)
+	}
+	return nil
+}
+
+// graphqlResponse is the body of a GraphQL response, with the envelope
+// already parsed.
+type graphqlResponse struct {
+	Body   []byte          `json:"-"`
+	Data   json.RawMessage `json:"data"`
+	Errors graphqlErrors   `json:"errors"`
+}
+
+// isEmptyData reports whether the data field of a GraphQL response has
+// nothing in it.
+func isEmptyData(data json.RawMessage) bool {
+	switch strings.TrimSpace(string(data)) {
+	case "", "null", "{}":
+		return true
+	}
+	return false
+}
+
+// send performs the HTTP request for a GraphQL query, retrying it
+// according to c.Retry, and returns the response.
+func (c *Client) send(ctx context.Context, queryName string, vars string) (*graphqlResponse, error) {
+	log := zerolog.Ctx(ctx)
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	// Authorizers and operation discovery use the same HTTP client if they
+	// need to make requests of their own.
+	ctx = withHTTPClient(ctx, client)
+	_, canReauth := c.Authorizer.(ResponseObserver)
+	canRediscover := true
+	for attempt := 1; ; attempt++ {
+		if err := c.waitForRateLimit(ctx, queryName); err != nil {
+			return nil, err
+		}
+		generation := registry.generation()
+		resp, r, err := c.sendOnce(ctx, queryName, vars)
+		if err == nil {
+			return r, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		if canRediscover && resp != nil && isStaleOperation(resp, err) {
+			// Most likely the web app was deployed since we discovered
+			// the query IDs and feature switches.
+			log.Warn().Err(err).Msgf("%s request failed, re-running GraphQL operation discovery", queryName)
+			if derr := registry.rediscover(ctx, generation); derr != nil {
+				log.Error().Err(derr).Msgf("Failed to re-run GraphQL operation discovery: %s", derr)
+				return nil, err
+			}
+			canRediscover = false
+			attempt--
+			continue
+		}
+		if canReauth && resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden || errors.Is(err, errBadGuestToken)) {
+			// The authorizer has seen the response and will get new
+			// credentials, so it's worth trying once more.
+			log.Debug().Msgf("Credentials were rejected, retrying: %s", err)
+			canReauth = false
+			attempt--
+			continue
+		}
+		delay, ok := c.Retry.delay(attempt, resp)
+		if !ok {
+			return nil, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return nil, err
+		}
+		log.Debug().Msgf("Attempt %d failed, retrying in %s: %s", attempt, delay, err)
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, err
+		case <-t.C:
+		}
+	}
+}
+
+// sendOnce makes a single HTTP request. The response is returned even if
+// the request failed, so that the caller can look at the status and headers.
+func (c *Client) sendOnce(ctx context.Context, queryName string, vars string) (*http.Response, *graphqlResponse, error) {
+	client := httpClientFromContext(ctx)
 
-	vars, features := userTweetsVarsAndFeatures(userID, cursor)
 	params := url.Values{}
 	params.Set("variables", vars)
-	params.Set("features", features)
+	params.Set("features", registry.features(queryName))
 
-	req, err := http.NewRequestWithContext(ctx, "GET", graphQLQueryUrl("UserTweets")+"?"+params.Encode(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", graphQLQueryUrl(queryName)+"?"+params.Encode(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("creating request object: %w", err)
+		return nil, nil, fmt.Errorf("creating request object: %w", err)
 	}
 
 	req.Header.Set("Accept", "*/*")
@@ -52,97 +182,77 @@ func (c *Client) UserTweets(ctx context.Context, userID string, cursor string) (
 
 	c.Authorizer.SetAuthHeader(req)
 
-	resp, err := c.Client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("sending HTTP request: %w", err)
+		return nil, nil, fmt.Errorf("sending HTTP request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if err := errorFromResponse(resp); err != nil {
-		return nil, fmt.Errorf("UserTweets: %w", err)
+	c.rateLimits.update(queryName, resp.Header)
+	if o, ok := c.Authorizer.(ResponseObserver); ok {
+		o.ObserveResponse(resp)
 	}
 
-	data := &userTweetsResponse{}
-	if err := json.NewDecoder(resp.Body).Decode(data); err != nil {
-		return nil, fmt.Errorf("unmarshaling JSON response: %w", err)
+	if err := errorFromResponse(resp); err != nil {
+		return resp, nil, err
 	}
 
-	r := &UserTweetsResponse{}
-	r.RawJSON, _ = json.Marshal(data)
-
-	v, err := data.Data.User.Result.Parse()
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("parsing data.user.result: %w", err)
+		// Treat it the same way as any other network error.
+		return nil, nil, fmt.Errorf("reading response: %w", err)
 	}
 
-	u, ok := v.(*graphqlUser)
-	if !ok {
-		return nil, fmt.Errorf("data.user.result has unexpected type %q", data.Data.User.Result.TypeName)
+	r := &graphqlResponse{Body: body}
+	if err := json.Unmarshal(body, r); err != nil {
+		return resp, nil, fmt.Errorf("unmarshaling JSON response: %w", err)
 	}
-
-	timeline := u.TimelineV2
-	if timeline == nil {
-		return nil, fmt.Errorf("no timeline found in the response")
+	// Some errors come with 200 OK status, but still have to be retried:
+	// the authorizer can't notice a rejected guest token in
+	// ObserveResponse, and missing feature switches need discovery.
+	switch {
+	case r.Errors.Is(errBadGuestToken):
+		if i, ok := c.Authorizer.(tokenInvalidator); ok {
+			i.invalidateToken(req)
+		}
+		return resp, nil, r.Errors.Err()
+	case r.Errors.Is(errFeaturesMissing) && isEmptyData(r.Data):
+		return resp, nil, r.Errors.Err()
 	}
+	return resp, r, nil
+}
 
-	for _, instr := range timeline.Timeline.Instructions {
-		if instr.Type != timelineAddEntries {
-			continue
-		}
-		for _, e := range instr.Entries {
-			c, err := e.Content.Parse()
-			if err != nil {
-				log.Info().Msgf("failed to parse instruction content: %s", err)
-				continue
-			}
-			switch c := c.(type) {
-			case *graphqlTimelineItem:
-				if c.ItemContent.TypeName != "TimelineTweet" {
-					break
-				}
-				t, err := c.ItemContent.Parse()
-				if err != nil {
-					log.Info().Msgf("failed to parse item content: %s", err)
-					break
-				}
-				ttw, ok := t.(*graphqlTimelineTweet)
-				if !ok {
-					log.Info().Msgf("item content has unexpected type %T", ttw)
-					break
-				}
-				if ttw.TweetResults == nil || ttw.TweetResults.Result == nil {
-					log.Debug().Msgf("missing tweet data in timeline tweet")
-					break
-				}
-				t, err = ttw.TweetResults.Result.Parse()
-				if err != nil {
-					log.Info().Msgf("failed to parse tweet results: %s", err)
-					break
-				}
-				tw, ok := t.(*graphqlTweet)
-				if !ok {
-					log.Info().Msgf("tweet results have unexpected type %T", tw)
-					break
-				}
-				if tw.Legacy.AuthorID != userID {
-					break
-				}
-				r.Tweets = append(r.Tweets, tw.Tweet())
-			case *graphqlTimelineCursor:
-				switch c.CursorType {
-				case "Top":
-					r.CursorPrev = c.Value
-				case "Bottom":
-					r.CursorNext = c.Value
-				}
-			}
+// parseUserResult extracts the user object from data.user.result, turning
+// missing and unavailable users into errors.
+func parseUserResult(result *graphqlObject, errs graphqlErrors) (*graphqlUser, error) {
+	if result == nil {
+		if err := errs.Err(); err != nil {
+			return nil, err
 		}
+		return nil, ErrUserNotFound
+	}
+
+	v, err := result.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("parsing data.user.result: %w", err)
 	}
-	for i, tw := range r.Tweets {
-		c.backfillMissingReferencedTweets(ctx, &tw)
-		r.Tweets[i] = tw
+
+	switch u := v.(type) {
+	case *graphqlUser:
+		return u, nil
+	case *graphqlUserUnavailable:
+		return nil, u.Err()
 	}
-	return r, nil
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("data.user.result has unexpected type %q", result.TypeName)
+}
+
+// isStaleOperation returns true if the request failed because of an
+// outdated query ID or a missing feature switch.
+func isStaleOperation(resp *http.Response, err error) bool {
+	return resp.StatusCode == http.StatusNotFound || errors.Is(err, errFeaturesMissing)
 }
 
 func errorFromResponse(resp *http.Response) error {
@@ -159,54 +269,48 @@ func errorFromResponse(resp *http.Response) error {
 			headers = append(headers, fmt.Sprintf("%s: %s", k, v))
 		}
 	}
+	parsed := struct {
+		Errors graphqlErrors `json:"errors"`
+	}{}
+	if err := json.Unmarshal(body, &parsed); err == nil && len(parsed.Errors) > 0 {
+		return fmt.Errorf("got error response: %d %s\n%s\n\n%w", resp.StatusCode, resp.Status, strings.Join(headers, "\n"), parsed.Errors.Err())
+	}
 	return fmt.Errorf("got error response: %d %s\n%s\n\n%s", resp.StatusCode, resp.Status, strings.Join(headers, "\n"), string(body))
 }
 
 type TweetDetailResponse struct {
 	RawJSON []byte
 	Tweet   twitter.Tweet
+	// Extended is the same tweet as Tweet, with the data that
+	// twitter.Tweet has no fields for.
+	Extended Tweet
 }
 
-func (c *Client) tweetDetail(ctx context.Context, tweetID string) (*TweetDetailResponse, error) {
-	if c.Client == nil {
-		c.Client = http.DefaultClient
-	}
+// TweetDetailOption changes what TweetDetail requests.
+type TweetDetailOption func(*tweetDetailOptions)
+
+type tweetDetailOptions struct {
+	communityNotes bool
+	// cursor is used to fetch more of the conversation.
+	cursor string
+}
 
+// WithCommunityNotes requests Community Notes (formerly Birdwatch) attached
+// to the tweet, see TweetNoIncludes.CommunityNote.
+func WithCommunityNotes() TweetDetailOption {
+	return func(o *tweetDetailOptions) { o.communityNotes = true }
+}
+
+func (c *Client) tweetDetail(ctx context.Context, tweetID string, opts tweetDetailOptions) (*TweetDetailResponse, error) {
 	log := zerolog.Ctx(ctx).With().
 		Str("tweet_id", tweetID).
 		Str("method", "TweetDetail").Logger()
 	ctx = log.WithContext(ctx)
 
-	vars, features := tweetDetailVarsAndFeatures(tweetID)
-	params := url.Values{}
-	params.Set("variables", vars)
-	params.Set("features", features)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", graphQLQueryUrl("TweetDetail")+"?"+params.Encode(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request object: %w", err)
-	}
-
-	req.Header.Set("Accept", "*/*")
-	req.Header.Set("content-type", "application/json")
-	req.Header.Set("Pragma", "no-cache")
-	req.Header.Set("Cache-Control", "no-cache")
-
-	c.Authorizer.SetAuthHeader(req)
-
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("sending HTTP request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if err := errorFromResponse(resp); err != nil {
-		return nil, fmt.Errorf("UserTweets: %w", err)
-	}
-
+	vars := tweetDetailVars(tweetID, opts)
 	data := &tweetDetailResponse{}
-	if err := json.NewDecoder(resp.Body).Decode(data); err != nil {
-		return nil, fmt.Errorf("unmarshaling JSON response: %w", err)
+	if err := c.graphQL(ctx, "TweetDetail", vars, data); err != nil {
+		return nil, err
 	}
 
 	r := &TweetDetailResponse{}
@@ -224,66 +328,98 @@ func (c *Client) tweetDetail(ctx context.Context, tweetID string) (*TweetDetailR
 			}
 			switch c := c.(type) {
 			case *graphqlTimelineItem:
-				if c.ItemContent.TypeName != "TimelineTweet" {
-					break
-				}
-				t, err := c.ItemContent.Parse()
-				if err != nil {
-					log.Info().Msgf("failed to parse item content: %s", err)
-					break
-				}
-				ttw, ok := t.(*graphqlTimelineTweet)
-				if !ok {
-					log.Info().Msgf("item content has unexpected type %T", ttw)
-					break
-				}
-				if ttw.TweetResults == nil || ttw.TweetResults.Result == nil {
-					log.Debug().Msgf("missing tweet data in timeline tweet")
+				tw := tweetFromItemContent(ctx, e.EntryID, c.ItemContent)
+				if tw == nil || tw.ID != tweetID {
 					break
 				}
-				t, err = ttw.TweetResults.Result.Parse()
-				if err != nil {
-					log.Info().Msgf("failed to parse tweet results: %s", err)
-					break
-				}
-				tw, ok := t.(*graphqlTweet)
-				if !ok {
-					log.Info().Msgf("tweet results have unexpected type %T", tw)
-					break
-				}
-				if tw.Legacy.ID != tweetID {
-					break
+				if t := tw.Tombstone; t != nil {
+					reason := t.Reason
+					if reason == "" {
+						reason = t.Text
+					}
+					return nil, fmt.Errorf("%w: %s", ErrTweetNotFound, reason)
 				}
 
-				r.Tweet = tw.Tweet()
-				r.RawJSON, _ = json.Marshal(ttw)
+				r.Extended = *tw
+				r.Tweet = tw.Twitter()
+				r.RawJSON, _ = json.Marshal(c.ItemContent)
 				return r, nil
 			}
 		}
 	}
 
+	if err := data.Errors.Err(); err != nil {
+		return nil, err
+	}
 	return nil, fmt.Errorf("requested tweet is missing from the response")
 }
 
-func (c *Client) TweetDetail(ctx context.Context, tweetID string) (*TweetDetailResponse, error) {
-	if c.Client == nil {
-		c.Client = http.DefaultClient
-	}
-
+func (c *Client) TweetDetail(ctx context.Context, tweetID string, opts ...TweetDetailOption) (*TweetDetailResponse, error) {
 	log := zerolog.Ctx(ctx).With().
 		Str("tweet_id", tweetID).
 		Str("method", "TweetDetail").Logger()
 	ctx = log.WithContext(ctx)
 
-	resp, err := c.tweetDetail(ctx, tweetID)
+	o := tweetDetailOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	resp, err := c.tweetDetail(ctx, tweetID, o)
 	if err != nil {
 		return nil, err
 	}
-	c.backfillMissingReferencedTweets(ctx, &resp.Tweet)
+	c.finishTweet(ctx, &resp.Extended)
+	resp.Tweet = resp.Extended.Twitter()
 	return resp, nil
 }
 
-func (c *Client) backfillMissingReferencedTweets(ctx context.Context, tw *twitter.Tweet) {
+// finishTweet prepares a converted tweet to be returned to the caller.
+func (c *Client) finishTweet(ctx context.Context, tw *Tweet) {
+	c.backfillMissingReferencedTweets(ctx, tw)
+	if c.UnescapeHTML {
+		tw.unescapeHTML()
+	}
+}
+
+type TweetEditHistoryResponse struct {
+	// Versions are all versions of the tweet, oldest first.
+	Versions []Tweet
+}
+
+// TweetEditHistory fetches every version of an edited tweet. tweetID can be
+// the ID of any of the versions. Tweets that were never edited have a single
+// version.
+func (c *Client) TweetEditHistory(ctx context.Context, tweetID string) (*TweetEditHistoryResponse, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("tweet_id", tweetID).
+		Str("method", "TweetEditHistory").Logger()
+	ctx = log.WithContext(ctx)
+
+	resp, err := c.TweetDetail(ctx, tweetID)
+	if err != nil {
+		return nil, err
+	}
+	ids := resp.Extended.EditHistoryTweetIDs
+	if len(ids) == 0 {
+		return &TweetEditHistoryResponse{Versions: []Tweet{resp.Extended}}, nil
+	}
+
+	r := &TweetEditHistoryResponse{}
+	for _, id := range ids {
+		if id == resp.Extended.ID {
+			r.Versions = append(r.Versions, resp.Extended)
+			continue
+		}
+		v, err := c.TweetDetail(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("fetching version %s: %w", id, err)
+		}
+		r.Versions = append(r.Versions, v.Extended)
+	}
+	return r, nil
+}
+
+func (c *Client) backfillMissingReferencedTweets(ctx context.Context, tw *Tweet) {
 	log := zerolog.Ctx(ctx)
 	refs := map[string]bool{}
 	for _, r := range tw.ReferencedTweets {
@@ -293,75 +429,68 @@ func (c *Client) backfillMissingReferencedTweets(ctx context.Context, tw *twitte
 		delete(refs, t.ID)
 	}
 	for id := range refs {
-		r, err := c.tweetDetail(ctx, id)
+		r, err := c.tweetDetail(ctx, id, tweetDetailOptions{})
 		if err != nil {
 			log.Info().Err(err).Msgf("Failed to fetch tweet %q: %s", id, err)
+			continue
 		}
-		// TODO(imax): merge in includes from r.Tweet
-		tw.Includes.Tweets = append(tw.Includes.Tweets, r.Tweet.TweetNoIncludes)
+		// TODO(imax): merge in includes from r.Extended
+		tw.Includes.Tweets = append(tw.Includes.Tweets, r.Extended.TweetNoIncludes)
 	}
 }
 
 func (c *Client) UserTweetsAndReplies(ctx context.Context, userID string, cursor string) (*UserTweetsResponse, error) {
-	if c.Client == nil {
-		c.Client = http.DefaultClient
-	}
+	return c.userTimeline(ctx, "UserTweetsAndReplies", userID, cursor)
+}
 
+func (c *Client) userTimeline(ctx context.Context, queryName string, userID string, cursor string) (*UserTweetsResponse, error) {
 	log := zerolog.Ctx(ctx).With().
 		Str("user_id", userID).
-		Str("method", "UserTweetsAndReplies").Logger()
+		Str("method", queryName).Logger()
 	ctx = log.WithContext(ctx)
 
-	vars, features := userTweetsVarsAndFeatures(userID, cursor)
-	params := url.Values{}
-	params.Set("variables", vars)
-	params.Set("features", features)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", graphQLQueryUrl("UserTweetsAndReplies")+"?"+params.Encode(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request object: %w", err)
-	}
-
-	req.Header.Set("Accept", "*/*")
-	req.Header.Set("content-type", "application/json")
-	req.Header.Set("Pragma", "no-cache")
-	req.Header.Set("Cache-Control", "no-cache")
-
-	c.Authorizer.SetAuthHeader(req)
-
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("sending HTTP request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if err := errorFromResponse(resp); err != nil {
-		return nil, fmt.Errorf("UserTweets: %w", err)
-	}
-
+	vars := userTweetsVars(userID, cursor)
 	data := &userTweetsResponse{}
-	if err := json.NewDecoder(resp.Body).Decode(data); err != nil {
-		return nil, fmt.Errorf("unmarshaling JSON response: %w", err)
+	if err := c.graphQL(ctx, queryName, vars, data); err != nil {
+		return nil, err
 	}
 
 	r := &UserTweetsResponse{}
 	r.RawJSON, _ = json.Marshal(data)
 
-	v, err := data.Data.User.Result.Parse()
+	u, err := parseUserResult(data.Data.User.Result, data.Errors)
 	if err != nil {
-		return nil, fmt.Errorf("parsing data.user.result: %w", err)
-	}
-
-	u, ok := v.(*graphqlUser)
-	if !ok {
-		return nil, fmt.Errorf("data.user.result has unexpected type %q", data.Data.User.Result.TypeName)
+		return nil, err
 	}
 
 	timeline := u.TimelineV2
 	if timeline == nil {
+		if err := data.Errors.Err(); err != nil {
+			return nil, err
+		}
 		return nil, fmt.Errorf("no timeline found in the response")
 	}
 
+	// ownEntry is true for top-level "tweet-" entries, which are the
+	// user's own tweets, as opposed to conversation modules that also
+	// contain tweets of others.
+	addTweet := func(tw *Tweet, ownEntry bool) {
+		if tw == nil {
+			return
+		}
+		// Tombstones don't have an author, but we keep the ones from the
+		// user's own entries so that the caller knows that something is
+		// missing.
+		if tw.Tombstone != nil {
+			if !ownEntry {
+				return
+			}
+		} else if tw.AuthorID != userID {
+			return
+		}
+		r.ExtendedTweets = append(r.ExtendedTweets, *tw)
+	}
+
 	for _, instr := range timeline.Timeline.Instructions {
 		if instr.Type != timelineAddEntries {
 			continue
@@ -374,56 +503,10 @@ func (c *Client) UserTweetsAndReplies(ctx context.Context, userID string, cursor
 			}
 			switch c := c.(type) {
 			case *graphqlTimelineItem:
-				if c.ItemContent.TypeName != "TimelineTweet" {
-					break
-				}
-				t, err := c.ItemContent.Parse()
-				if err != nil {
-					log.Info().Msgf("failed to parse item content: %s", err)
-					break
-				}
-				ttw, ok := t.(*graphqlTimelineTweet)
-				if !ok {
-					log.Info().Msgf("item content has unexpected type %T", ttw)
-					break
-				}
-				if ttw.TweetResults == nil || ttw.TweetResults.Result == nil {
-					log.Debug().Msgf("missing tweet data in timeline tweet")
-					break
-				}
-				t, err = ttw.TweetResults.Result.Parse()
-				if err != nil {
-					log.Info().Msgf("failed to parse tweet results: %s", err)
-					break
-				}
-				tw, ok := t.(*graphqlTweet)
-				if !ok {
-					log.Info().Msgf("tweet results have unexpected type %T", tw)
-					break
-				}
-				if tw.Legacy.AuthorID != userID {
-					break
-				}
-				r.Tweets = append(r.Tweets, tw.Tweet())
+				addTweet(tweetFromItemContent(ctx, e.EntryID, c.ItemContent), strings.HasPrefix(e.EntryID, "tweet-"))
 			case *graphqlTimelineModule:
 				for _, i := range c.Items {
-					if i.Item.ItemContent == nil {
-						continue
-					}
-					t, err := i.Item.ItemContent.Parse()
-					if err != nil {
-						log.Info().Err(err).Msgf("parsing item.itemContent")
-						continue
-					}
-					tw, ok := t.(*graphqlTweet)
-					if !ok {
-						log.Info().Msgf("itemContent has unexpected type %T", tw)
-						break
-					}
-					if tw.Legacy.AuthorID != userID {
-						break
-					}
-					r.Tweets = append(r.Tweets, tw.Tweet())
+					addTweet(tweetFromItemContent(ctx, i.EntryID, i.Item.ItemContent), false)
 				}
 			case *graphqlTimelineCursor:
 				switch c.CursorType {
@@ -435,74 +518,117 @@ func (c *Client) UserTweetsAndReplies(ctx context.Context, userID string, cursor
 			}
 		}
 	}
-	for i, tw := range r.Tweets {
-		c.backfillMissingReferencedTweets(ctx, &tw)
-		r.Tweets[i] = tw
+	for i := range r.ExtendedTweets {
+		c.finishTweet(ctx, &r.ExtendedTweets[i])
+		// twitter.Tweet has no place for the reason, so tombstones are
+		// only returned in ExtendedTweets.
+		if r.ExtendedTweets[i].Tombstone == nil {
+			r.Tweets = append(r.Tweets, r.ExtendedTweets[i].Twitter())
+		}
 	}
 	return r, nil
 }
 
+// tweetFromItemContent converts the content of a timeline item into a tweet.
+// Unavailable tweets are returned as placeholders with a tombstone. It
+// returns nil if the item doesn't contain a tweet.
+func tweetFromItemContent(ctx context.Context, entryID string, content *graphqlObject) *Tweet {
+	log := zerolog.Ctx(ctx)
+	if content == nil || content.TypeName != "TimelineTweet" {
+		return nil
+	}
+	t, err := content.Parse()
+	if err != nil {
+		log.Info().Msgf("failed to parse item content: %s", err)
+		return nil
+	}
+	ttw, ok := t.(*graphqlTimelineTweet)
+	if !ok {
+		log.Info().Msgf("item content has unexpected type %T", t)
+		return nil
+	}
+	if ttw.TweetResults == nil || ttw.TweetResults.Result == nil {
+		log.Debug().Msgf("missing tweet data in timeline tweet")
+		return nil
+	}
+	tw, tombstone, err := parseTweetResult(ttw.TweetResults.Result)
+	if err != nil {
+		log.Info().Msgf("failed to parse tweet results: %s", err)
+		return nil
+	}
+	if tombstone != nil {
+		// Entry IDs look like "tweet-123" or "conversationthread-123-tweet-456".
+		i := strings.LastIndex(entryID, "tweet-")
+		if i < 0 {
+			log.Info().Msgf("can't find tweet ID in entry ID %q", entryID)
+			return nil
+		}
+		r := tombstoneTweet(entryID[i+len("tweet-"):], tombstone)
+		return &r
+	}
+	r := tw.Tweet()
+	return &r
+}
+
 type UserByScreenNameResponse struct {
 	RawJSON []byte
 	ID      string
+	User    User
 }
 
 func (c *Client) UserByScreenName(ctx context.Context, username string) (*UserByScreenNameResponse, error) {
-	if c.Client == nil {
-		c.Client = http.DefaultClient
-	}
-
 	log := zerolog.Ctx(ctx).With().
 		Str("user_id", username).
 		Str("method", "UserByScreenName").Logger()
 	ctx = log.WithContext(ctx)
 
-	vars, features := userByScreenNameVarsAndFeatures(username)
-	params := url.Values{}
-	params.Set("variables", vars)
-	params.Set("features", features)
+	vars := userByScreenNameVars(username)
+	data := &userByScreenNameResponse{}
+	if err := c.graphQL(ctx, "UserByScreenName", vars, data); err != nil {
+		return nil, err
+	}
+
+	r := &UserByScreenNameResponse{}
+	r.RawJSON, _ = json.Marshal(data)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", graphQLQueryUrl("UserByScreenName")+"?"+params.Encode(), nil)
+	u, err := parseUserResult(data.Data.User.Result, data.Errors)
 	if err != nil {
-		return nil, fmt.Errorf("creating request object: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Accept", "*/*")
-	req.Header.Set("content-type", "application/json")
-	req.Header.Set("Pragma", "no-cache")
-	req.Header.Set("Cache-Control", "no-cache")
+	r.ID = u.RestID
+	r.User = u.User()
 
-	c.Authorizer.SetAuthHeader(req)
+	return r, nil
+}
 
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("sending HTTP request: %w", err)
-	}
-	defer resp.Body.Close()
+type UserByRestIDResponse struct {
+	RawJSON []byte
+	User    User
+}
 
-	if err := errorFromResponse(resp); err != nil {
-		return nil, fmt.Errorf("UserTweets: %w", err)
-	}
+// UserByRestID fetches the profile of a user by their numeric ID.
+func (c *Client) UserByRestID(ctx context.Context, userID string) (*UserByRestIDResponse, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("user_id", userID).
+		Str("method", "UserByRestId").Logger()
+	ctx = log.WithContext(ctx)
 
-	data := &userByScreenNameResponse{}
-	if err := json.NewDecoder(resp.Body).Decode(data); err != nil {
-		return nil, fmt.Errorf("unmarshaling JSON response: %w", err)
+	vars := userByRestIDVars(userID)
+	data := &userByRestIDResponse{}
+	if err := c.graphQL(ctx, "UserByRestId", vars, data); err != nil {
+		return nil, err
 	}
 
-	r := &UserByScreenNameResponse{}
+	r := &UserByRestIDResponse{}
 	r.RawJSON, _ = json.Marshal(data)
 
-	v, err := data.Data.User.Result.Parse()
+	u, err := parseUserResult(data.Data.User.Result, data.Errors)
 	if err != nil {
-		return nil, fmt.Errorf("parsing data.user.result: %w", err)
-	}
-
-	u, ok := v.(*graphqlUser)
-	if !ok {
-		return nil, fmt.Errorf("data.user.result has unexpected type %q", data.Data.User.Result.TypeName)
+		return nil, err
 	}
 
-	r.ID = u.RestID
+	r.User = u.User()
 
 	return r, nil
 }
@@ -0,0 +1,184 @@
+package pwitter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testEditedTweet returns timeline item content with a tweet that has
+// edit_control.
+func testEditedTweet(id string, text string, editControl string) string {
+	return testTimelineTweetResult(fmt.Sprintf(`{"__typename":"Tweet","rest_id":"%[1]s","edit_control":%[3]s,`+
+		`"legacy":{"id_str":"%[1]s","full_text":%[2]q,"user_id_str":"783214"}}`, id, text, editControl))
+}
+
+// tweetDetailRequestVars returns the variables of a TweetDetail request.
+func tweetDetailRequestVars(t *testing.T, r *http.Request) tweetDetailVariables {
+	t.Helper()
+	vars := tweetDetailVariables{}
+	if err := json.Unmarshal([]byte(r.URL.Query().Get("variables")), &vars); err != nil {
+		t.Errorf("unmarshaling variables: %s", err)
+	}
+	return vars
+}
+
+func TestTweetEditHistory(t *testing.T) {
+	const initial = `{"edit_tweet_ids":["1","2"],"editable_until_msecs":"1682000000000","edits_remaining":"4","is_edit_eligible":true}`
+	versions := map[string]string{
+		"1": testTweetDetailEntries(testTimelineItem("tweet-1", testEditedTweet("1", "Helo", initial))),
+		"2": testTweetDetailEntries(testTimelineItem("tweet-2", testEditedTweet("2", "Hello", `{"initial_tweet_id":"1","edit_control_initial":`+initial+`}`))),
+	}
+	client, count := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		resp, ok := versions[tweetDetailRequestVars(t, r).ID]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, resp)
+	})
+
+	r, err := client.TweetEditHistory(context.Background(), "2")
+	if err != nil {
+		t.Fatalf("TweetEditHistory returned error: %s", err)
+	}
+	var got []string
+	for _, v := range r.Versions {
+		got = append(got, v.Text)
+	}
+	if diff := cmp.Diff([]string{"Helo", "Hello"}, got); diff != "" {
+		t.Errorf("unexpected versions (-want +got):\n%s", diff)
+	}
+	if *count != 2 {
+		t.Errorf("expected 2 requests, got %d", *count)
+	}
+
+	want := &EditControls{
+		EditsRemaining: 4,
+		IsEditEligible: true,
+		EditableUntil:  "2023-04-20T14:13:20.000Z",
+	}
+	for _, v := range r.Versions {
+		if diff := cmp.Diff([]string{"1", "2"}, v.EditHistoryTweetIDs); diff != "" {
+			t.Errorf("unexpected edit_history_tweet_ids of %s (-want +got):\n%s", v.ID, diff)
+		}
+		if diff := cmp.Diff(want, v.EditControls); diff != "" {
+			t.Errorf("unexpected edit_controls of %s (-want +got):\n%s", v.ID, diff)
+		}
+	}
+}
+
+func TestTweetDetailMissingReferencedTweet(t *testing.T) {
+	client, count := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		if tweetDetailRequestVars(t, r).ID != "2" {
+			fmt.Fprint(w, `{"data":{},"errors":[{"code":144,"message":"No status found with that ID."}]}`)
+			return
+		}
+		fmt.Fprint(w, testTweetDetailEntries(testTimelineItem("tweet-2", testTimelineTweet("2", "1"))))
+	})
+
+	r, err := client.TweetDetail(context.Background(), "2")
+	if err != nil {
+		t.Fatalf("TweetDetail returned error: %s", err)
+	}
+	if r.Tweet.ID != "2" || len(r.Tweet.Includes.Tweets) != 0 {
+		t.Errorf("got tweet %q with includes %+v, want 2 without includes", r.Tweet.ID, r.Tweet.Includes.Tweets)
+	}
+	if *count != 2 {
+		t.Errorf("expected 2 requests, got %d", *count)
+	}
+}
+
+func TestUserTweetsAndRepliesTombstones(t *testing.T) {
+	tombstone := testTimelineTombstone("This Tweet was deleted")
+	client, _ := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":{"user":{"result":{"__typename":"User","rest_id":"42","timeline_v2":{"timeline":{"instructions":[`+
+			`{"type":"TimelineAddEntries","entries":[`+strings.Join([]string{
+			testTimelineItem("tweet-1", testTimelineTweetBy("1", "", "42")),
+			testTimelineItem("tweet-2", tombstone),
+			testModule("profile-conversation-3",
+				testModuleItem("profile-conversation-3-tweet-3", tombstone),
+				testModuleItem("profile-conversation-3-tweet-4", testTimelineTweetBy("4", "3", "783214")),
+				testModuleItem("profile-conversation-3-tweet-5", testTimelineTweetBy("5", "4", "42"))),
+		}, ",")+`]}]}}}}}}`)
+	})
+
+	r, err := client.UserTweetsAndReplies(context.Background(), "42", "")
+	if err != nil {
+		t.Fatalf("UserTweetsAndReplies returned error: %s", err)
+	}
+	var got []string
+	for _, tw := range r.ExtendedTweets {
+		got = append(got, tw.ID)
+	}
+	if diff := cmp.Diff([]string{"1", "2", "5"}, got); diff != "" {
+		t.Errorf("unexpected tweets (-want +got):\n%s", diff)
+	}
+	got = nil
+	for _, tw := range r.Tweets {
+		got = append(got, tw.ID)
+	}
+	if diff := cmp.Diff([]string{"1", "5"}, got); diff != "" {
+		t.Errorf("unexpected twitter-threads tweets (-want +got):\n%s", diff)
+	}
+}
+
+func TestTweetDetailTombstone(t *testing.T) {
+	client, _ := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testTweetDetailEntries(testTimelineItem("tweet-1", testTimelineTombstone("This Tweet was deleted by the Tweet author."))))
+	})
+
+	_, err := client.TweetDetail(context.Background(), "1")
+	if !errors.Is(err, ErrTweetNotFound) {
+		t.Fatalf("TweetDetail returned error %v, want %v", err, ErrTweetNotFound)
+	}
+	if !strings.Contains(err.Error(), "deleted by the Tweet author") {
+		t.Errorf("error %q doesn't include the tombstone text", err)
+	}
+}
+
+func TestTweetDetailCommunityNotes(t *testing.T) {
+	resp := testTweetDetailEntries(testTimelineItem("tweet-1", testTimelineTweetResult(
+		`{"__typename":"Tweet","rest_id":"1","legacy":{"id_str":"1","full_text":"The Moon is made of cheese","user_id_str":"783214"},`+
+			`"birdwatch_pivot":{"title":"Readers added context they thought people might want to know","shorttitle":"Readers added context",`+
+			`"destinationUrl":"https://twitter.com/i/birdwatch/n/1650000000000000011","note":{"rest_id":"1650000000000000011"},`+
+			`"subtitle":{"text":"The Moon is made of rock. nasa.gov/moon nasa.gov/moon","entities":[`+
+			`{"fromIndex":26,"toIndex":39,"ref":{"type":"TimelineUrl","url":"https://t.co/moon","urlType":"ExternalUrl"}},`+
+			`{"fromIndex":40,"toIndex":53,"ref":{"type":"TimelineUrl","url":"https://t.co/moon","urlType":"ExternalUrl"}}]}}}`)))
+	var withNotes bool
+	client, _ := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		withNotes = tweetDetailRequestVars(t, r).WithBirdwatchNotes
+		fmt.Fprint(w, resp)
+	})
+
+	if _, err := client.TweetDetail(context.Background(), "1"); err != nil {
+		t.Fatalf("TweetDetail returned error: %s", err)
+	}
+	if withNotes {
+		t.Errorf("notes are requested without WithCommunityNotes")
+	}
+
+	r, err := client.TweetDetail(context.Background(), "1", WithCommunityNotes())
+	if err != nil {
+		t.Fatalf("TweetDetail returned error: %s", err)
+	}
+	if !withNotes {
+		t.Errorf("notes are not requested with WithCommunityNotes")
+	}
+	want := &CommunityNote{
+		ID:      "1650000000000000011",
+		Title:   "Readers added context they thought people might want to know",
+		Text:    "The Moon is made of rock. nasa.gov/moon nasa.gov/moon",
+		Sources: []string{"https://t.co/moon"},
+		URL:     "https://twitter.com/i/birdwatch/n/1650000000000000011",
+	}
+	if diff := cmp.Diff(want, r.Extended.CommunityNote); diff != "" {
+		t.Errorf("unexpected community note (-want +got):\n%s", diff)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -9,12 +10,55 @@ import (
 	"github.com/rusni-pyzda/pwitter"
 )
 
-func run() error {
+var authCache = flag.String("auth-cache", "", "File to keep anonymous credentials in between runs")
+
+// loadAuth reads cached credentials, or obtains new ones if there are none.
+func loadAuth(ctx context.Context) (*pwitter.AnonymousAuthorizer, error) {
+	if *authCache != "" {
+		b, err := os.ReadFile(*authCache)
+		if err == nil {
+			auth := &pwitter.AnonymousAuthorizer{}
+			uerr := json.Unmarshal(b, auth)
+			if uerr == nil {
+				return auth, nil
+			}
+			fmt.Fprintf(os.Stderr, "Ignoring invalid credentials in %q: %s\n", *authCache, uerr)
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("reading credentials cache: %w", err)
+		}
+	}
+	return pwitter.AnonymousAuth(ctx)
+}
+
+// saveAuth writes the credentials back, since they could have been
+// refreshed while running.
+func saveAuth(auth *pwitter.AnonymousAuthorizer) error {
+	if *authCache == "" {
+		return nil
+	}
+	b, err := json.Marshal(auth)
+	if err != nil {
+		return fmt.Errorf("marshaling credentials: %w", err)
+	}
+	if err := os.WriteFile(*authCache, b, 0600); err != nil {
+		return fmt.Errorf("writing credentials cache: %w", err)
+	}
+	return nil
+}
+
+func run() (err error) {
 	ctx := context.Background()
-	auth, err := pwitter.AnonymousAuth(ctx)
+	auth, err := loadAuth(ctx)
 	if err != nil {
 		return fmt.Errorf("construction anonymous authorizer: %w", err)
 	}
+	// Save even if the command failed, so that a refreshed guest token
+	// isn't thrown away.
+	defer func() {
+		if serr := saveAuth(auth); serr != nil && err == nil {
+			err = serr
+		}
+	}()
 
 	client := &pwitter.Client{Authorizer: auth}
 
@@ -25,10 +69,19 @@ func run() error {
 			return fmt.Errorf("fetching tweet: %w", err)
 		}
 		fmt.Printf("%s\n", t.RawJSON)
+	case "thread":
+		t, err := client.AuthorThread(ctx, flag.Arg(1))
+		if err != nil {
+			return fmt.Errorf("fetching thread: %w", err)
+		}
+		b, err := json.Marshal(t.Twitter())
+		if err != nil {
+			return fmt.Errorf("marshaling thread: %w", err)
+		}
+		fmt.Printf("%s\n", b)
 	default:
 		return fmt.Errorf("unknown command")
 	}
-
 	return nil
 }
 
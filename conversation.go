@@ -0,0 +1,286 @@
+package pwitter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+// ConversationNode is a tweet in a conversation tree.
+type ConversationNode struct {
+	Tweet Tweet
+	// Parent is the tweet this one replies to. It is nil for the root.
+	Parent  *ConversationNode `json:"-"`
+	Replies []*ConversationNode
+}
+
+type ConversationResponse struct {
+	// Root is the first tweet of the conversation.
+	Root *ConversationNode
+	// Focal is the requested tweet.
+	Focal *ConversationNode
+}
+
+// DefaultConversationMaxPages is the number of pages Conversation fetches,
+// unless configured otherwise with WithMaxPages.
+const DefaultConversationMaxPages = 20
+
+// ConversationOption changes how much of a conversation is fetched.
+type ConversationOption func(*conversationOptions)
+
+type conversationOptions struct {
+	maxPages int
+}
+
+// WithMaxPages limits the number of requests Conversation makes. Zero or
+// less means no limit.
+func WithMaxPages(n int) ConversationOption {
+	return func(o *conversationOptions) { o.maxPages = n }
+}
+
+// Conversation fetches the conversation around a tweet: the tweets it
+// replies to up to the root, and the replies, following "show more"
+// cursors until there are none left or DefaultConversationMaxPages pages
+// were fetched. Unavailable tweets are kept as placeholders with a
+// tombstone, so that the tree stays connected.
+func (c *Client) Conversation(ctx context.Context, tweetID string, opts ...ConversationOption) (*ConversationResponse, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("tweet_id", tweetID).
+		Str("method", "Conversation").Logger()
+	ctx = log.WithContext(ctx)
+
+	o := conversationOptions{maxPages: DefaultConversationMaxPages}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	b := newConversationBuilder(tweetID)
+	cursor := ""
+	for page := 1; ; page++ {
+		if err := c.addConversationPage(ctx, b, cursor); err != nil {
+			if cursor == "" {
+				return nil, err
+			}
+			// Return what we've got so far rather than nothing.
+			log.Info().Err(err).Msgf("Failed to fetch more of the conversation: %s", err)
+			break
+		}
+
+		if len(b.cursors) == 0 {
+			break
+		}
+		if o.maxPages > 0 && page >= o.maxPages {
+			log.Info().Msgf("Stopping after %d pages, %d more cursors left", page, len(b.cursors))
+			break
+		}
+		cursor, b.cursors = b.cursors[0], b.cursors[1:]
+	}
+
+	return c.finishConversation(ctx, b), nil
+}
+
+// finishConversation builds the tree and finishes every tweet in it. Tweets
+// get their parents included first, so that those don't have to be fetched
+// again.
+func (c *Client) finishConversation(ctx context.Context, b *conversationBuilder) *ConversationResponse {
+	r := b.build()
+	for _, id := range b.order {
+		n := b.nodes[id]
+		if n.Parent != nil && n.Parent.Tweet.ID == n.Tweet.InReplyTo() {
+			addIncludedTweet(&n.Tweet, n.Parent.Tweet.TweetNoIncludes)
+		}
+	}
+	for _, id := range b.order {
+		c.finishTweet(ctx, &b.nodes[id].Tweet)
+	}
+	return r
+}
+
+// addConversationPage fetches a page of the conversation around the focal
+// tweet of b and adds it to b. The first page, without a cursor, must
+// contain the focal tweet.
+func (c *Client) addConversationPage(ctx context.Context, b *conversationBuilder, cursor string) error {
+	vars := tweetDetailVars(b.focalID, tweetDetailOptions{cursor: cursor})
+	data := &tweetDetailResponse{}
+	if err := c.graphQL(ctx, "TweetDetail", vars, data); err != nil {
+		return err
+	}
+	b.addInstructions(ctx, data.Data.ThreadedConversationWithInjectionsV2.Instructions)
+	if cursor == "" && b.nodes[b.focalID] == nil {
+		if err := data.Errors.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("requested tweet is missing from the response")
+	}
+	return nil
+}
+
+type conversationBuilder struct {
+	focalID string
+	nodes   map[string]*ConversationNode
+	// order is the order in which tweets appeared in the responses.
+	order []string
+	// prev is the ID of the tweet that preceded each tweet in the
+	// response. It is used as the parent if the tweet doesn't say what it
+	// replies to, e.g. because it's a tombstone.
+	prev map[string]string
+	// lastInModule is the last tweet of every conversation module, more
+	// items can be added to the module later.
+	lastInModule map[string]string
+	// modules are the IDs of conversation modules in the order they
+	// appeared, and firstInModule is the first tweet of each of them.
+	modules       []string
+	firstInModule map[string]string
+	// moduleCursors is the latest "show more" cursor of every module.
+	moduleCursors map[string]string
+
+	cursors     []string
+	seenCursors map[string]bool
+}
+
+func newConversationBuilder(focalID string) *conversationBuilder {
+	return &conversationBuilder{
+		focalID:       focalID,
+		nodes:         map[string]*ConversationNode{},
+		prev:          map[string]string{},
+		lastInModule:  map[string]string{},
+		firstInModule: map[string]string{},
+		moduleCursors: map[string]string{},
+		seenCursors:   map[string]bool{},
+	}
+}
+
+func (b *conversationBuilder) addInstructions(ctx context.Context, instrs []timelineInstruction) {
+	log := zerolog.Ctx(ctx)
+	for _, instr := range instrs {
+		switch instr.Type {
+		case timelineAddEntries:
+			// Top-level tweets are the ancestors of the focal tweet and
+			// the focal tweet itself, in order.
+			prev := ""
+			for _, e := range instr.Entries {
+				c, err := e.Content.Parse()
+				if err != nil {
+					log.Info().Msgf("failed to parse instruction content: %s", err)
+					continue
+				}
+				switch c := c.(type) {
+				case *graphqlTimelineItem:
+					prev = b.addItem(ctx, e.EntryID, c.ItemContent, prev)
+				case *graphqlTimelineModule:
+					b.addModuleItems(ctx, e.EntryID, c.Items)
+				case *graphqlTimelineCursor:
+					b.addCursor(c)
+				}
+			}
+		case timelineAddToModule:
+			b.addModuleItems(ctx, instr.ModuleEntryID, instr.ModuleItems)
+		}
+	}
+}
+
+// addModuleItems adds a chain of replies. The first item replies to the
+// focal tweet, unless the module already has some items.
+func (b *conversationBuilder) addModuleItems(ctx context.Context, moduleID string, items []graphqlTimelineModuleItem) {
+	prev := b.lastInModule[moduleID]
+	if prev == "" {
+		prev = b.focalID
+	}
+	if _, ok := b.firstInModule[moduleID]; !ok {
+		b.modules = append(b.modules, moduleID)
+		b.firstInModule[moduleID] = ""
+	}
+	for _, i := range items {
+		if c := itemCursor(i.Item.ItemContent); c != nil && c.CursorType == "ShowMore" {
+			b.moduleCursors[moduleID] = c.Value
+		}
+		id := b.addItem(ctx, i.EntryID, i.Item.ItemContent, prev)
+		if id != prev && b.firstInModule[moduleID] == "" {
+			b.firstInModule[moduleID] = id
+		}
+		prev = id
+	}
+	b.lastInModule[moduleID] = prev
+}
+
+// itemCursor returns the cursor if the item content is one.
+func itemCursor(content *graphqlObject) *graphqlTimelineCursor {
+	if content == nil || content.TypeName != "TimelineTimelineCursor" {
+		return nil
+	}
+	c, err := content.Parse()
+	if err != nil {
+		return nil
+	}
+	return c.(*graphqlTimelineCursor)
+}
+
+// addItem adds a tweet or a cursor and returns the ID of the tweet that
+// should precede the next item.
+func (b *conversationBuilder) addItem(ctx context.Context, entryID string, content *graphqlObject, prev string) string {
+	if content == nil {
+		return prev
+	}
+	if c := itemCursor(content); c != nil {
+		b.addCursor(c)
+		return prev
+	}
+	tw := tweetFromItemContent(ctx, entryID, content)
+	if tw == nil {
+		return prev
+	}
+	if b.nodes[tw.ID] == nil {
+		b.nodes[tw.ID] = &ConversationNode{Tweet: *tw}
+		b.order = append(b.order, tw.ID)
+		b.prev[tw.ID] = prev
+	}
+	return tw.ID
+}
+
+func (b *conversationBuilder) addCursor(c *graphqlTimelineCursor) {
+	switch c.CursorType {
+	case "Top", "Bottom", "ShowMore", "ShowMoreThreads", "ShowMoreThreadsPrompt":
+	default:
+		return
+	}
+	if c.Value == "" || b.seenCursors[c.Value] {
+		return
+	}
+	b.seenCursors[c.Value] = true
+	b.cursors = append(b.cursors, c.Value)
+}
+
+// build links the tweets into a tree. Tweets are attached to the tweet
+// they reply to, or to the preceding tweet if the former is not known.
+func (b *conversationBuilder) build() *ConversationResponse {
+	for _, id := range b.order {
+		n := b.nodes[id]
+		p := b.nodes[n.Tweet.InReplyTo()]
+		if p == nil {
+			p = b.nodes[b.prev[id]]
+		}
+		if p == nil || p.hasAncestor(n) {
+			continue
+		}
+		n.Parent = p
+		p.Replies = append(p.Replies, n)
+	}
+
+	r := &ConversationResponse{Focal: b.nodes[b.focalID]}
+	r.Root = r.Focal
+	for r.Root.Parent != nil {
+		r.Root = r.Root.Parent
+	}
+	return r
+}
+
+// hasAncestor reports whether a is n or one of its ancestors.
+func (n *ConversationNode) hasAncestor(a *ConversationNode) bool {
+	for ; n != nil; n = n.Parent {
+		if n == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package pwitter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func testTimelineTweet(id string, replyTo string) string {
+	return testTimelineTweetBy(id, replyTo, "783214")
+}
+
+func testTimelineTweetBy(id string, replyTo string, authorID string) string {
+	return testTimelineTweetResult(fmt.Sprintf(`{"__typename":"Tweet","rest_id":"%[1]s",`+
+		`"legacy":{"id_str":"%[1]s","full_text":"tweet %[1]s","user_id_str":"%[3]s","conversation_id_str":"1","in_reply_to_status_id_str":"%[2]s"}}`,
+		id, replyTo, authorID))
+}
+
+// testTimelineTweetResult wraps a tweet result into timeline item content.
+func testTimelineTweetResult(result string) string {
+	return `{"__typename":"TimelineTweet","tweet_results":{"result":` + result + `}}`
+}
+
+func testTimelineTombstone(text string) string {
+	return testTimelineTweetResult(fmt.Sprintf(`{"__typename":"TweetTombstone","tombstone":{"text":{"text":%q}}}`, text))
+}
+
+func testTimelineCursor(cursorType string, value string) string {
+	return fmt.Sprintf(`{"__typename":"TimelineTimelineCursor","cursorType":"%s","value":"%s"}`, cursorType, value)
+}
+
+func testTimelineItem(entryID string, content string) string {
+	return fmt.Sprintf(`{"entryId":"%s","content":{"__typename":"TimelineTimelineItem","itemContent":%s}}`, entryID, content)
+}
+
+func testModuleItem(entryID string, content string) string {
+	return fmt.Sprintf(`{"entryId":"%s","item":{"itemContent":%s}}`, entryID, content)
+}
+
+func testTweetDetailInstructions(instrs ...string) string {
+	return `{"data":{"threaded_conversation_with_injections_v2":{"instructions":[` + strings.Join(instrs, ",") + `]}}}`
+}
+
+func testModule(entryID string, items ...string) string {
+	return fmt.Sprintf(`{"entryId":"%s","content":{"__typename":"TimelineTimelineModule","items":[%s]}}`, entryID, strings.Join(items, ","))
+}
+
+func testAddToModule(moduleID string, items ...string) string {
+	return `{"type":"TimelineAddToModule","moduleEntryId":"` + moduleID + `","moduleItems":[` + strings.Join(items, ",") + `]}`
+}
+
+// testTweetDetailEntries returns a TweetDetail response with a single
+// TimelineAddEntries instruction.
+func testTweetDetailEntries(entries ...string) string {
+	return testTweetDetailInstructions(`{"type":"TimelineAddEntries","entries":[` + strings.Join(entries, ",") + `]}`)
+}
+
+// conversationIDs returns the tree as nested lists of tweet IDs.
+func conversationIDs(n *ConversationNode) string {
+	r := n.Tweet.ID
+	if len(n.Replies) == 0 {
+		return r
+	}
+	var replies []string
+	for _, c := range n.Replies {
+		replies = append(replies, conversationIDs(c))
+	}
+	return r + "(" + strings.Join(replies, " ") + ")"
+}
+
+func TestConversation(t *testing.T) {
+	pages := map[string]string{
+		"": testTweetDetailEntries(
+			testTimelineItem("tweet-1", testTimelineTweet("1", "")),
+			testTimelineItem("tweet-2", testTimelineTweet("2", "1")),
+			testModule("conversationthread-3",
+				testModuleItem("conversationthread-3-tweet-3", testTimelineTweet("3", "2")),
+				testModuleItem("conversationthread-3-tweet-4", testTimelineTweet("4", "3")),
+				testModuleItem("conversationthread-3-cursor-showmore-1", testTimelineCursor("ShowMore", "more"))),
+			testTimelineItem("cursor-bottom-1", testTimelineCursor("Bottom", "bottom")),
+		),
+		"more": testTweetDetailInstructions(testAddToModule("conversationthread-3",
+			testModuleItem("conversationthread-3-tweet-5", testTimelineTombstone("This Tweet was deleted")))),
+		"bottom": testTweetDetailEntries(
+			testTimelineItem("tweet-2", testTimelineTweet("2", "1")),
+			testModule("conversationthread-6",
+				testModuleItem("conversationthread-6-tweet-6", testTimelineTweet("6", "2"))),
+			testTimelineItem("cursor-bottom-2", testTimelineCursor("Bottom", "bottom")),
+		),
+	}
+	client, count := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		vars := tweetDetailRequestVars(t, r)
+		if vars.ID != "2" {
+			t.Errorf("unexpected focal tweet ID %q", vars.ID)
+		}
+		resp, ok := pages[vars.Cursor]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, resp)
+	})
+
+	r, err := client.Conversation(context.Background(), "2")
+	if err != nil {
+		t.Fatalf("Conversation returned error: %s", err)
+	}
+	if diff := cmp.Diff("1(2(3(4(5)) 6))", conversationIDs(r.Root)); diff != "" {
+		t.Errorf("unexpected conversation tree (-want +got):\n%s", diff)
+	}
+	if r.Focal.Tweet.ID != "2" || r.Focal.Parent != r.Root {
+		t.Errorf("focal tweet is %q with parent %v, want 2 with parent 1", r.Focal.Tweet.ID, r.Focal.Parent)
+	}
+	// Parents are included without fetching them again.
+	if inc := r.Focal.Tweet.Includes.Tweets; len(inc) != 1 || inc[0].ID != "1" {
+		t.Errorf("focal tweet includes %+v, want its parent", inc)
+	}
+	if *count != 3 {
+		t.Errorf("expected 3 requests, got %d", *count)
+	}
+}
+
+func TestConversationMaxPages(t *testing.T) {
+	client, count := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		// Every page has a new cursor, so only the limit stops it.
+		fmt.Fprint(w, testTweetDetailEntries(
+			testTimelineItem("tweet-1", testTimelineTweet("1", "")),
+			testTimelineItem(fmt.Sprintf("cursor-bottom-%d", n), testTimelineCursor("Bottom", fmt.Sprintf("bottom%d", n))),
+		))
+	})
+
+	r, err := client.Conversation(context.Background(), "1", WithMaxPages(3))
+	if err != nil {
+		t.Fatalf("Conversation returned error: %s", err)
+	}
+	if r.Focal.Tweet.ID != "1" {
+		t.Errorf("got focal tweet %q, want 1", r.Focal.Tweet.ID)
+	}
+	if *count != 3 {
+		t.Errorf("expected 3 requests, got %d", *count)
+	}
+}
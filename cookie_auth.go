@@ -0,0 +1,180 @@
+package pwitter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CookieAuthorizer authorizes requests as a logged in user, using the
+// cookies of an existing browser session. It keeps track of ct0 cookie
+// rotations. It is safe for concurrent use.
+type CookieAuthorizer struct {
+	mu          sync.Mutex
+	bearerToken string
+	cookies     map[string]string
+	createdAt   time.Time
+}
+
+// CookieAuth creates an authorizer from the values of auth_token and ct0
+// cookies.
+func CookieAuth(ctx context.Context, authToken string, ct0 string) (*CookieAuthorizer, error) {
+	return newCookieAuthorizer(ctx, map[string]string{
+		"auth_token": authToken,
+		"ct0":        ct0,
+	})
+}
+
+// CookieAuthFromFile creates an authorizer from twitter.com cookies in a
+// file in Netscape cookies.txt format, as exported by browser extensions.
+func CookieAuthFromFile(ctx context.Context, path string) (*CookieAuthorizer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cookies, err := parseCookiesTxt(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", path, err)
+	}
+	return newCookieAuthorizer(ctx, cookies)
+}
+
+func newCookieAuthorizer(ctx context.Context, cookies map[string]string) (*CookieAuthorizer, error) {
+	if cookies["auth_token"] == "" || cookies["ct0"] == "" {
+		return nil, fmt.Errorf("auth_token/ct0 must be not empty (%q/%q)", cookies["auth_token"], cookies["ct0"])
+	}
+
+	client := &http.Client{Transport: httpClientFromContext(ctx).Transport}
+	html, err := fetchHomePage(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	js, err := fetchMainJS(ctx, client, html)
+	if err != nil {
+		return nil, err
+	}
+	registry.update(ctx, html, js)
+	bearerToken, err := findBearerToken(js)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CookieAuthorizer{
+		bearerToken: bearerToken,
+		cookies:     cookies,
+		createdAt:   time.Now(),
+	}, nil
+}
+
+// parseCookiesTxt reads cookies for twitter.com from a file in Netscape
+// cookies.txt format.
+func parseCookiesTxt(r io.Reader) (map[string]string, error) {
+	cookies := map[string]string{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		// HttpOnly cookies are written with this prefix, making them look
+		// like comments.
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("malformed line %q", line)
+		}
+		domain := strings.TrimPrefix(fields[0], ".")
+		if domain != "twitter.com" && !strings.HasSuffix(domain, ".twitter.com") {
+			continue
+		}
+		cookies[fields[5]] = fields[6]
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return cookies, nil
+}
+
+// CreatedAt returns the time when the authorizer was created.
+func (a *CookieAuthorizer) CreatedAt() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.createdAt
+}
+
+type cookieAuthorizerJSON struct {
+	BearerToken string            `json:"bearer_token"`
+	Cookies     map[string]string `json:"cookies"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// MarshalJSON serializes the session, including any cookie updates
+// received from the server.
+func (a *CookieAuthorizer) MarshalJSON() ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return json.Marshal(cookieAuthorizerJSON{
+		BearerToken: a.bearerToken,
+		Cookies:     a.cookies,
+		CreatedAt:   a.createdAt,
+	})
+}
+
+func (a *CookieAuthorizer) UnmarshalJSON(b []byte) error {
+	v := cookieAuthorizerJSON{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.BearerToken == "" || v.Cookies["auth_token"] == "" || v.Cookies["ct0"] == "" {
+		return fmt.Errorf("bearer_token and auth_token/ct0 cookies must be not empty")
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.bearerToken = v.BearerToken
+	a.cookies = v.Cookies
+	a.createdAt = v.CreatedAt
+	return nil
+}
+
+// ObserveResponse picks up the new ct0 value when the server rotates it.
+func (a *CookieAuthorizer) ObserveResponse(resp *http.Response) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, c := range resp.Cookies() {
+		if c.Name != "ct0" || c.Value == "" {
+			continue
+		}
+		a.cookies["ct0"] = c.Value
+	}
+}
+
+func (a *CookieAuthorizer) SetAuthHeader(req *http.Request) {
+	a.mu.Lock()
+	names := make([]string, 0, len(a.cookies))
+	for name := range a.cookies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	cookies := []string{}
+	for _, name := range names {
+		cookies = append(cookies, (&http.Cookie{Name: name, Value: a.cookies[name]}).String())
+	}
+	ct0 := a.cookies["ct0"]
+	bearerToken := a.bearerToken
+	a.mu.Unlock()
+
+	req.Header.Set("Cookie", strings.Join(cookies, "; "))
+	req.Header.Set("authorization", fmt.Sprintf("Bearer %s", bearerToken))
+	req.Header.Set("x-csrf-token", ct0)
+	req.Header.Set("x-twitter-auth-type", "OAuth2Session")
+	req.Header.Set("DNT", "1")
+}
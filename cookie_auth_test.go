@@ -0,0 +1,58 @@
+package pwitter
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseCookiesTxt(t *testing.T) {
+	const input = `# Netscape HTTP Cookie File
+# This is a generated file!  Do not edit.
+
+.twitter.com	TRUE	/	TRUE	1735689600	ct0	abcdef
+#HttpOnly_.twitter.com	TRUE	/	TRUE	1735689600	auth_token	123456
+.example.com	TRUE	/	FALSE	1735689600	ct0	other
+api.twitter.com	FALSE	/	TRUE	1735689600	twid	u%3D42
+`
+	got, err := parseCookiesTxt(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parseCookiesTxt returned error: %s", err)
+	}
+	want := map[string]string{
+		"ct0":        "abcdef",
+		"auth_token": "123456",
+		"twid":       "u%3D42",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected cookies (-want +got):\n%s", diff)
+	}
+
+	if _, err := parseCookiesTxt(strings.NewReader("twitter.com\tct0\n")); err == nil {
+		t.Errorf("parseCookiesTxt accepted a malformed line")
+	}
+}
+
+func TestCookieAuthorizerRotatesCSRFToken(t *testing.T) {
+	a := &CookieAuthorizer{
+		bearerToken: "AAAAAAAAAbearer",
+		cookies:     map[string]string{"auth_token": "123456", "ct0": "old"},
+	}
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Add("Set-Cookie", "ct0=new; Path=/; Domain=.twitter.com; Secure")
+	a.ObserveResponse(resp)
+
+	req, _ := http.NewRequest("GET", "https://twitter.com/", nil)
+	a.SetAuthHeader(req)
+	if got := req.Header.Get("x-csrf-token"); got != "new" {
+		t.Errorf("x-csrf-token = %q, want %q", got, "new")
+	}
+	if got := req.Header.Get("Cookie"); got != "auth_token=123456; ct0=new" {
+		t.Errorf("unexpected Cookie header %q", got)
+	}
+	if got := req.Header.Get("x-twitter-auth-type"); got != "OAuth2Session" {
+		t.Errorf("x-twitter-auth-type = %q, want %q", got, "OAuth2Session")
+	}
+}
@@ -1,8 +1,16 @@
 package pwitter
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"regexp"
+	"sync"
+
+	"github.com/rs/zerolog"
 )
 
 var (
@@ -16,12 +24,183 @@ var (
 )
 
 const (
-	// TODO: extract from html on the fly
+	// twitterFeatures is used for operations that we don't have the list
+	// of feature switches for, and as a source of values for switches that
+	// are missing from the HTML page.
 	twitterFeatures = `{"blue_business_profile_image_shape_enabled":true,"responsive_web_graphql_exclude_directive_enabled":true,"verified_phone_label_enabled":false,"responsive_web_graphql_timeline_navigation_enabled":true,"responsive_web_graphql_skip_user_profile_image_extensions_enabled":false,"tweetypie_unmention_optimization_enabled":true,"vibe_api_enabled":true,"responsive_web_edit_tweet_api_enabled":true,"graphql_is_translatable_rweb_tweet_is_translatable_enabled":true,"view_counts_everywhere_api_enabled":true,"longform_notetweets_consumption_enabled":true,"tweet_awards_web_tipping_enabled":false,"freedom_of_speech_not_reach_fetch_enabled":false,"standardized_nudges_misinfo":true,"tweet_with_visibility_results_prefer_gql_limited_actions_policy_enabled":false,"interactive_text_enabled":true,"responsive_web_text_conversations_enabled":false,"longform_notetweets_rich_text_read_enabled":true,"responsive_web_enhance_cards_enabled":false}`
 )
 
+// graphqlOperation is the metadata of a GraphQL operation that the web app
+// knows about.
+type graphqlOperation struct {
+	QueryID  string
+	Features []string
+}
+
+// operationRegistry keeps GraphQL query IDs and feature switches discovered
+// at runtime. They change with every deployment of the web app, so we can't
+// rely on graphqlID and twitterFeatures being up to date.
+type operationRegistry struct {
+	mu            sync.RWMutex
+	operations    map[string]graphqlOperation
+	featureValues map[string]json.RawMessage
+	// gen is incremented on every update.
+	gen int
+
+	// discoveryMu makes sure that only one discovery runs at a time.
+	discoveryMu sync.Mutex
+}
+
+// registry is shared by all Clients and authorizers: the operations are the
+// same for everyone, and a discovery triggered by one Client benefits the
+// others.
+var registry = &operationRegistry{}
+
+func (r *operationRegistry) queryID(queryName string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if op, ok := r.operations[queryName]; ok {
+		return op.QueryID
+	}
+	return graphqlID[queryName]
+}
+
+// features returns the JSON-encoded feature switches for the operation.
+func (r *operationRegistry) features(queryName string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	op, ok := r.operations[queryName]
+	if !ok || len(op.Features) == 0 {
+		return twitterFeatures
+	}
+
+	fallback := map[string]json.RawMessage{}
+	_ = json.Unmarshal([]byte(twitterFeatures), &fallback)
+
+	features := map[string]json.RawMessage{}
+	for _, name := range op.Features {
+		switch {
+		case r.featureValues[name] != nil:
+			features[name] = r.featureValues[name]
+		case fallback[name] != nil:
+			features[name] = fallback[name]
+		default:
+			features[name] = json.RawMessage("false")
+		}
+	}
+	b, _ := json.Marshal(features)
+	return string(b)
+}
+
+// update extracts operations from the main js file of the web app, and
+// feature switch values from the HTML page.
+func (r *operationRegistry) update(ctx context.Context, html []byte, js []byte) {
+	log := zerolog.Ctx(ctx)
+
+	ops := parseOperations(js)
+	log.Debug().Msgf("Discovered %d GraphQL operations", len(ops))
+	values, err := parseFeatureValues(html)
+	if err != nil {
+		log.Info().Err(err).Msgf("Failed to extract feature switch values: %s", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(ops) > 0 {
+		r.operations = ops
+	}
+	if len(values) > 0 {
+		r.featureValues = values
+	}
+	r.gen++
+}
+
+func (r *operationRegistry) generation() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.gen
+}
+
+// rediscover fetches the web app and updates the registry, unless it was
+// already updated since the given generation. This way many requests that
+// failed at the same time cause only one discovery.
+func (r *operationRegistry) rediscover(ctx context.Context, since int) error {
+	r.discoveryMu.Lock()
+	defer r.discoveryMu.Unlock()
+	if r.generation() != since {
+		return nil
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return fmt.Errorf("creating a cookie jar: %w", err)
+	}
+	client := &http.Client{Jar: jar, Transport: httpClientFromContext(ctx).Transport}
+	html, err := fetchHomePage(ctx, client)
+	if err != nil {
+		return err
+	}
+	js, err := fetchMainJS(ctx, client, html)
+	if err != nil {
+		return err
+	}
+	r.update(ctx, html, js)
+	return nil
+}
+
+func parseOperations(js []byte) map[string]graphqlOperation {
+	ops := map[string]graphqlOperation{}
+	re := regexp.MustCompile(`queryId:"([^"]+)",operationName:"([^"]+)"(,operationType:"[^"]*",metadata:\{featureSwitches:\[([^\]]*)\])?`)
+	nameRe := regexp.MustCompile(`"([^"]+)"`)
+	for _, m := range re.FindAllSubmatch(js, -1) {
+		op := graphqlOperation{QueryID: string(m[1])}
+		for _, f := range nameRe.FindAllSubmatch(m[4], -1) {
+			op.Features = append(op.Features, string(f[1]))
+		}
+		ops[string(m[2])] = op
+	}
+	return ops
+}
+
+// parseFeatureValues extracts values of feature switches from the
+// `__INITIAL_STATE__` object in the HTML page. User-specific values take
+// precedence over the defaults.
+func parseFeatureValues(html []byte) (map[string]json.RawMessage, error) {
+	const marker = "window.__INITIAL_STATE__="
+	i := bytes.Index(html, []byte(marker))
+	if i < 0 {
+		return nil, fmt.Errorf("didn't find __INITIAL_STATE__ in the html page")
+	}
+
+	type config map[string]struct {
+		Value json.RawMessage `json:"value"`
+	}
+	state := struct {
+		FeatureSwitch struct {
+			DefaultConfig config `json:"defaultConfig"`
+			User          struct {
+				Config config `json:"config"`
+			} `json:"user"`
+		} `json:"featureSwitch"`
+	}{}
+	// Decoder stops after the first value, ignoring the rest of the script.
+	if err := json.NewDecoder(bytes.NewReader(html[i+len(marker):])).Decode(&state); err != nil {
+		return nil, fmt.Errorf("unmarshaling __INITIAL_STATE__: %w", err)
+	}
+
+	values := map[string]json.RawMessage{}
+	for _, c := range []config{state.FeatureSwitch.DefaultConfig, state.FeatureSwitch.User.Config} {
+		for name, v := range c {
+			if v.Value != nil {
+				values[name] = v.Value
+			}
+		}
+	}
+	return values, nil
+}
+
 func graphQLQueryUrl(queryName string) string {
-	return fmt.Sprintf("https://twitter.com/i/api/graphql/%s/%s", graphqlID[queryName], queryName)
+	return fmt.Sprintf("https://twitter.com/i/api/graphql/%s/%s", registry.queryID(queryName), queryName)
 }
 
 type userTweetsVariables struct {
@@ -34,7 +213,7 @@ type userTweetsVariables struct {
 	Cursor                                 string `json:"cursor,omitempty"`
 }
 
-func userTweetsVarsAndFeatures(userID string, cursor string) (string, string) {
+func userTweetsVars(userID string, cursor string) string {
 	v := &userTweetsVariables{
 		UserID:                                 userID,
 		Count:                                  40,
@@ -46,18 +225,16 @@ func userTweetsVarsAndFeatures(userID string, cursor string) (string, string) {
 	}
 
 	vars, _ := json.Marshal(v)
-	return string(vars), twitterFeatures
+	return string(vars)
 }
 
-type errors []json.RawMessage
-
 type userTweetsResponse struct {
 	Data struct {
 		User struct {
 			Result *graphqlObject `json:"result"`
 		} `json:"user"`
 	} `json:"data"`
-	Errors errors `json:"errors,omitempty"`
+	Errors graphqlErrors `json:"errors,omitempty"`
 }
 
 type tweetDetailVariables struct {
@@ -69,9 +246,10 @@ type tweetDetailVariables struct {
 	WithV2Timeline                         bool   `json:"withV2Timeline"`
 	WithCommunity                          bool   `json:"withCommunity"`
 	WithBirdwatchNotes                     bool   `json:"withBirdwatchNotes"`
+	Cursor                                 string `json:"cursor,omitempty"`
 }
 
-func tweetDetailVarsAndFeatures(id string) (string, string) {
+func tweetDetailVars(id string, opts tweetDetailOptions) string {
 	v := &tweetDetailVariables{
 		ID:                                     id,
 		IncludePromotedContent:                 true,
@@ -79,10 +257,12 @@ func tweetDetailVarsAndFeatures(id string) (string, string) {
 		WithVoice:                              true,
 		WithV2Timeline:                         true,
 		WithCommunity:                          true,
+		WithBirdwatchNotes:                     opts.communityNotes,
+		Cursor:                                 opts.cursor,
 	}
 
 	vars, _ := json.Marshal(v)
-	return string(vars), twitterFeatures
+	return string(vars)
 }
 
 type tweetDetailResponse struct {
@@ -91,7 +271,7 @@ type tweetDetailResponse struct {
 			Instructions []timelineInstruction `json:"instructions"`
 		} `json:"threaded_conversation_with_injections_v2"`
 	} `json:"data"`
-	Errors errors `json:"errors,omitempty"`
+	Errors graphqlErrors `json:"errors,omitempty"`
 }
 
 type userByScreenNameVariables struct {
@@ -99,14 +279,14 @@ type userByScreenNameVariables struct {
 	WithSafetyModeUserFields bool   `json:"withSafetyModeUserFields"`
 }
 
-func userByScreenNameVarsAndFeatures(username string) (string, string) {
+func userByScreenNameVars(username string) string {
 	v := &userByScreenNameVariables{
 		ScreenName:               username,
 		WithSafetyModeUserFields: true,
 	}
 
 	vars, _ := json.Marshal(v)
-	return string(vars), twitterFeatures
+	return string(vars)
 }
 
 type userByScreenNameResponse struct {
@@ -115,5 +295,29 @@ type userByScreenNameResponse struct {
 			Result *graphqlObject `json:"result"`
 		} `json:"user"`
 	} `json:"data"`
-	Errors errors `json:"errors,omitempty"`
+	Errors graphqlErrors `json:"errors,omitempty"`
+}
+
+type userByRestIDVariables struct {
+	UserID                   string `json:"userId"`
+	WithSafetyModeUserFields bool   `json:"withSafetyModeUserFields"`
+}
+
+func userByRestIDVars(id string) string {
+	v := &userByRestIDVariables{
+		UserID:                   id,
+		WithSafetyModeUserFields: true,
+	}
+
+	vars, _ := json.Marshal(v)
+	return string(vars)
+}
+
+type userByRestIDResponse struct {
+	Data struct {
+		User struct {
+			Result *graphqlObject `json:"result"`
+		} `json:"user"`
+	} `json:"data"`
+	Errors graphqlErrors `json:"errors,omitempty"`
 }
@@ -0,0 +1,133 @@
+package pwitter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Ukraine-DAO/twitter-threads/twitter"
+)
+
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrUserSuspended = errors.New("user is suspended")
+	ErrTweetNotFound = errors.New("tweet not found")
+	ErrProtected     = errors.New("account is protected")
+
+	errFeaturesMissing = errors.New("required feature switches are missing")
+	errBadGuestToken   = errors.New("guest token is invalid")
+)
+
+// Error codes that Twitter puts into the `errors` array of GraphQL responses.
+const (
+	errCodeUserNotFound  = 50
+	errCodeUserSuspended = 63
+	errCodeRateLimited   = 88
+	errCodeTweetNotFound = 144
+	errCodeProtected     = 179
+	errCodeBadGuestToken = 239
+	// Also used for other kinds of invalid requests, so we have to look at
+	// the message too.
+	errCodeInvalidRequest = 336
+)
+
+// GraphQLError is a single entry of the `errors` array returned by the
+// GraphQL API. It can be matched against ErrUserNotFound, ErrUserSuspended,
+// ErrTweetNotFound, ErrProtected and twitter.ErrThrottled with errors.Is.
+type GraphQLError struct {
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+	Kind    string        `json:"kind,omitempty"`
+	Name    string        `json:"name,omitempty"`
+}
+
+func (e *GraphQLError) Error() string {
+	if len(e.Path) == 0 {
+		return fmt.Sprintf("graphql error %d (%s): %s", e.Code, e.Kind, e.Message)
+	}
+	path := make([]string, len(e.Path))
+	for i, p := range e.Path {
+		path[i] = fmt.Sprint(p)
+	}
+	return fmt.Sprintf("graphql error %d (%s) at %s: %s", e.Code, e.Kind, strings.Join(path, "."), e.Message)
+}
+
+func (e *GraphQLError) Is(target error) bool {
+	switch target {
+	case ErrUserNotFound:
+		return e.Code == errCodeUserNotFound
+	case ErrUserSuspended:
+		return e.Code == errCodeUserSuspended
+	case ErrTweetNotFound:
+		return e.Code == errCodeTweetNotFound
+	case ErrProtected:
+		return e.Code == errCodeProtected
+	case twitter.ErrThrottled:
+		return e.Code == errCodeRateLimited
+	case errBadGuestToken:
+		return e.Code == errCodeBadGuestToken
+	case errFeaturesMissing:
+		return e.Code == errCodeInvalidRequest && strings.Contains(e.Message, "features cannot be null")
+	}
+	return false
+}
+
+type graphqlErrors []GraphQLError
+
+// Err returns nil if there are no errors, the error itself if there is
+// only one, or all of them combined otherwise.
+func (errs graphqlErrors) Err() error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return &errs[0]
+	default:
+		return errs
+	}
+}
+
+func (errs graphqlErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i := range errs {
+		msgs[i] = errs[i].Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (errs graphqlErrors) Is(target error) bool {
+	for i := range errs {
+		if errors.Is(&errs[i], target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (errs graphqlErrors) As(target interface{}) bool {
+	for i := range errs {
+		if errors.As(&errs[i], target) {
+			return true
+		}
+	}
+	return false
+}
+
+// graphqlUserUnavailable is returned in place of a user object for
+// suspended and otherwise inaccessible accounts.
+type graphqlUserUnavailable struct {
+	Reason string `json:"reason"`
+}
+
+func (u *graphqlUserUnavailable) Err() error {
+	switch u.Reason {
+	case "Suspended":
+		return ErrUserSuspended
+	case "Protected":
+		return ErrProtected
+	case "NotFound":
+		return ErrUserNotFound
+	}
+	return fmt.Errorf("user is unavailable: %s", u.Reason)
+}
@@ -0,0 +1,129 @@
+package pwitter
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Ukraine-DAO/twitter-threads/twitter"
+)
+
+func TestGraphQLErrorIs(t *testing.T) {
+	sentinels := []error{ErrUserNotFound, ErrUserSuspended, ErrTweetNotFound, ErrProtected, twitter.ErrThrottled, errFeaturesMissing, errBadGuestToken}
+	cases := []struct {
+		err  GraphQLError
+		want error
+	}{
+		{GraphQLError{Code: 50, Message: "User not found."}, ErrUserNotFound},
+		{GraphQLError{Code: 63, Message: "User has been suspended."}, ErrUserSuspended},
+		{GraphQLError{Code: 88, Message: "Rate limit exceeded"}, twitter.ErrThrottled},
+		{GraphQLError{Code: 144, Message: "No status found with that ID."}, ErrTweetNotFound},
+		{GraphQLError{Code: 179, Message: "Sorry, you are not authorized to see this status."}, ErrProtected},
+		{GraphQLError{Code: 239, Message: "Bad guest token"}, errBadGuestToken},
+		{GraphQLError{Code: 336, Message: "The following features cannot be null: foo"}, errFeaturesMissing},
+		{GraphQLError{Code: 336, Message: "Something else is wrong with the request"}, nil},
+		{GraphQLError{Code: 0, Message: "Unknown error"}, nil},
+	}
+	for _, tc := range cases {
+		for _, s := range sentinels {
+			if got, want := errors.Is(&tc.err, s), s == tc.want; got != want {
+				t.Errorf("errors.Is(%q, %q) = %v, want %v", tc.err.Error(), s, got, want)
+			}
+		}
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	if err := graphqlErrors(nil).Err(); err != nil {
+		t.Errorf("Err() of no errors = %v, want nil", err)
+	}
+
+	one := graphqlErrors{{Code: 144, Message: "No status found with that ID."}}
+	if _, ok := one.Err().(*GraphQLError); !ok {
+		t.Errorf("Err() of a single error = %T, want *GraphQLError", one.Err())
+	}
+
+	errs := graphqlErrors{
+		{Code: 336, Message: "Something else is wrong with the request"},
+		{Code: 63, Message: "User has been suspended.", Path: []interface{}{"user", "result"}},
+	}
+	err := errs.Err()
+	if !errors.Is(err, ErrUserSuspended) {
+		t.Errorf("errors.Is(%q, ErrUserSuspended) = false, want true", err)
+	}
+	if errors.Is(err, ErrUserNotFound) {
+		t.Errorf("errors.Is(%q, ErrUserNotFound) = true, want false", err)
+	}
+	var gqlErr *GraphQLError
+	if !errors.As(err, &gqlErr) {
+		t.Fatalf("errors.As(%q, *GraphQLError) = false, want true", err)
+	}
+	if gqlErr.Code != 336 {
+		t.Errorf("errors.As returned error with code %d, want the first one", gqlErr.Code)
+	}
+	for _, e := range errs {
+		if !strings.Contains(err.Error(), e.Message) {
+			t.Errorf("Error() = %q doesn't mention %q", err.Error(), e.Message)
+		}
+	}
+}
+
+func TestParseUserResult(t *testing.T) {
+	cases := []struct {
+		name   string
+		result string
+		errs   graphqlErrors
+		want   error
+	}{
+		{
+			name:   "user",
+			result: `{"__typename":"User","rest_id":"783214"}`,
+		},
+		{
+			name:   "suspended",
+			result: `{"__typename":"UserUnavailable","reason":"Suspended"}`,
+			want:   ErrUserSuspended,
+		},
+		{
+			name:   "protected",
+			result: `{"__typename":"UserUnavailable","reason":"Protected"}`,
+			want:   ErrProtected,
+		},
+		{
+			name:   "unavailable not found",
+			result: `{"__typename":"UserUnavailable","reason":"NotFound"}`,
+			want:   ErrUserNotFound,
+		},
+		{
+			name: "missing",
+			want: ErrUserNotFound,
+		},
+		{
+			name: "missing with errors",
+			errs: graphqlErrors{{Code: 63, Message: "User has been suspended."}},
+			want: ErrUserSuspended,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var result *graphqlObject
+			if tc.result != "" {
+				result = &graphqlObject{}
+				if err := json.Unmarshal([]byte(tc.result), result); err != nil {
+					t.Fatalf("unmarshaling result: %s", err)
+				}
+			}
+			u, err := parseUserResult(result, tc.errs)
+			if tc.want == nil {
+				if err != nil || u == nil || u.RestID != "783214" {
+					t.Errorf("parseUserResult() = %+v, %v, want the user", u, err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.want) {
+				t.Errorf("parseUserResult() returned error %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package pwitter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	testMainJS = `e.exports={queryId:"abcDEF123",operationName:"UserTweets",operationType:"query",metadata:{featureSwitches:["known_enabled","known_disabled","verified_phone_label_enabled","unknown"],fieldToggles:[]}}},` +
+		`52:e=>{e.exports={queryId:"xyz_-789",operationName:"SearchTimeline",operationType:"query"}}`
+	testHTML = `<script>window.__INITIAL_STATE__={"featureSwitch":{"defaultConfig":{"known_enabled":{"value":false},"known_disabled":{"value":false}},"user":{"config":{"known_enabled":{"value":true}}}}};window.__META_DATA__={};</script>`
+)
+
+func TestOperationRegistry(t *testing.T) {
+	r := &operationRegistry{}
+	if got, want := r.queryID("UserTweets"), graphqlID["UserTweets"]; got != want {
+		t.Errorf("queryID before discovery = %q, want %q", got, want)
+	}
+	if got := r.features("UserTweets"); got != twitterFeatures {
+		t.Errorf("features before discovery = %s, want the built-in set", got)
+	}
+
+	r.update(context.Background(), []byte(testHTML), []byte(testMainJS))
+	for name, want := range map[string]string{
+		"UserTweets":     "abcDEF123",
+		"SearchTimeline": "xyz_-789",
+		"TweetDetail":    graphqlID["TweetDetail"],
+	} {
+		if got := r.queryID(name); got != want {
+			t.Errorf("queryID(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	got := map[string]bool{}
+	if err := json.Unmarshal([]byte(r.features("UserTweets")), &got); err != nil {
+		t.Fatalf("features are not valid JSON: %s", err)
+	}
+	want := map[string]bool{
+		"known_enabled":                true,
+		"known_disabled":               false,
+		"verified_phone_label_enabled": false,
+		"unknown":                      false,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected features (-want +got):\n%s", diff)
+	}
+	if got := r.features("TweetDetail"); got != twitterFeatures {
+		t.Errorf("features for an unknown operation = %s, want the built-in set", got)
+	}
+}
+
+func TestIsStaleOperation(t *testing.T) {
+	cases := []struct {
+		status int
+		body   string
+		want   bool
+	}{
+		{http.StatusNotFound, ``, true},
+		{http.StatusBadRequest, `{"errors":[{"message":"The following features cannot be null: foo_enabled","code":336}]}`, true},
+		{http.StatusBadRequest, `{"errors":[{"message":"Variable \"$userId\" got invalid value","code":336}]}`, false},
+		{http.StatusForbidden, `{"errors":[{"message":"Bad guest token.","code":239}]}`, false},
+	}
+	for _, tc := range cases {
+		resp := &http.Response{
+			StatusCode: tc.status,
+			Status:     http.StatusText(tc.status),
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader(tc.body)),
+		}
+		err := errorFromResponse(resp)
+		if got := isStaleOperation(resp, err); got != tc.want {
+			t.Errorf("isStaleOperation(%d, %s) = %v, want %v", tc.status, tc.body, got, tc.want)
+		}
+	}
+}
+
+func TestRediscoverOnMissingFeatures(t *testing.T) {
+	old := registry
+	registry = &operationRegistry{}
+	t.Cleanup(func() { registry = old })
+
+	var requested []string
+	client, _ := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch {
+		case r.URL.Path == "/":
+			fmt.Fprint(w, testHTML+`<script src="https://abs.twimg.com/responsive-web/client-web/main.abc123.js"></script>`)
+		case strings.HasSuffix(r.URL.Path, ".js"):
+			fmt.Fprint(w, testMainJS)
+		case n == 1:
+			// Errors come with 200 OK status.
+			fmt.Fprint(w, `{"data":{},"errors":[{"message":"The following features cannot be null: known_enabled","code":336}]}`)
+		default:
+			if got := r.URL.Query().Get("features"); !strings.Contains(got, "known_enabled") {
+				t.Errorf("request after discovery has features %s", got)
+			}
+			fmt.Fprint(w, `{"data":{"user":{"result":{"__typename":"User","rest_id":"42","timeline_v2":{"timeline":{"instructions":[]}}}}}}`)
+		}
+	})
+
+	if _, err := client.UserTweets(context.Background(), "42", ""); err != nil {
+		t.Fatalf("UserTweets returned error: %s", err)
+	}
+	want := []string{
+		"/i/api/graphql/" + graphqlID["UserTweets"] + "/UserTweets",
+		"/",
+		"/responsive-web/client-web/main.abc123.js",
+		"/i/api/graphql/abcDEF123/UserTweets",
+	}
+	if diff := cmp.Diff(want, requested); diff != "" {
+		t.Errorf("unexpected requests (-want +got):\n%s", diff)
+	}
+}
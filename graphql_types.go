@@ -3,6 +3,7 @@ package pwitter
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/Ukraine-DAO/twitter-threads/twitter"
@@ -10,12 +11,16 @@ import (
 
 var (
 	graphqlType = map[string]func() interface{}{
-		"TimelineTimelineModule": func() interface{} { return &graphqlTimelineModule{} },
-		"TimelineTimelineCursor": func() interface{} { return &graphqlTimelineCursor{} },
-		"TimelineTimelineItem":   func() interface{} { return &graphqlTimelineItem{} },
-		"TimelineTweet":          func() interface{} { return &graphqlTimelineTweet{} },
-		"Tweet":                  func() interface{} { return &graphqlTweet{} },
-		"User":                   func() interface{} { return &graphqlUser{} },
+		"TimelineTimelineModule":     func() interface{} { return &graphqlTimelineModule{} },
+		"TimelineTimelineCursor":     func() interface{} { return &graphqlTimelineCursor{} },
+		"TimelineTimelineItem":       func() interface{} { return &graphqlTimelineItem{} },
+		"TimelineTweet":              func() interface{} { return &graphqlTimelineTweet{} },
+		"Tweet":                      func() interface{} { return &graphqlTweet{} },
+		"TweetWithVisibilityResults": func() interface{} { return &graphqlTweetWithVisibilityResults{} },
+		"TweetTombstone":             func() interface{} { return &graphqlTweetTombstone{} },
+		"TweetUnavailable":           func() interface{} { return &graphqlTweetUnavailable{} },
+		"User":                       func() interface{} { return &graphqlUser{} },
+		"UserUnavailable":            func() interface{} { return &graphqlUserUnavailable{} },
 	}
 )
 
@@ -66,33 +71,77 @@ func (o *graphqlObject) Parse() (parsedGraphqlObject, error) {
 }
 
 type graphqlUser struct {
-	ID         string `json:"id,omitempty"`
-	RestID     string `json:"rest_id,omitempty"`
-	TimelineV2 *struct {
+	ID             string `json:"id,omitempty"`
+	RestID         string `json:"rest_id,omitempty"`
+	IsBlueVerified bool   `json:"is_blue_verified,omitempty"`
+	TimelineV2     *struct {
 		Timeline struct {
 			Instructions []timelineInstruction `json:"instructions"`
 		} `json:"timeline"`
 	} `json:"timeline_v2,omitempty"`
-	Legacy *graphqlUserLegacy `json:"legacy,omitempty"`
+	Legacy       *graphqlUserLegacy `json:"legacy,omitempty"`
+	Professional *struct {
+		ProfessionalType string `json:"professional_type"`
+		Category         []struct {
+			Name string `json:"name"`
+		} `json:"category"`
+	} `json:"professional,omitempty"`
+	AffiliatesHighlightedLabel struct {
+		Label *struct {
+			Description string `json:"description"`
+			URL         struct {
+				URL string `json:"url"`
+			} `json:"url"`
+			Badge struct {
+				URL string `json:"url"`
+			} `json:"badge"`
+			UserLabelType string `json:"userLabelType"`
+		} `json:"label,omitempty"`
+	} `json:"affiliates_highlighted_label,omitempty"`
 }
 
 type graphqlUserLegacy struct {
-	Name       string `json:"name"`
-	ScreenName string `json:"screen_name"`
+	Name                 string `json:"name"`
+	ScreenName           string `json:"screen_name"`
+	Description          string `json:"description,omitempty"`
+	Location             string `json:"location,omitempty"`
+	URL                  string `json:"url,omitempty"`
+	CreatedAt            string `json:"created_at,omitempty"`
+	FollowersCount       int    `json:"followers_count,omitempty"`
+	FriendsCount         int    `json:"friends_count,omitempty"`
+	StatusesCount        int    `json:"statuses_count,omitempty"`
+	ListedCount          int    `json:"listed_count,omitempty"`
+	Verified             bool   `json:"verified,omitempty"`
+	VerifiedType         string `json:"verified_type,omitempty"`
+	Protected            bool   `json:"protected,omitempty"`
+	ProfileImageURLHTTPS string `json:"profile_image_url_https,omitempty"`
+	ProfileBannerURL     string `json:"profile_banner_url,omitempty"`
+	Entities             struct {
+		URL struct {
+			URLs []entityURL `json:"urls,omitempty"`
+		} `json:"url,omitempty"`
+		Description struct {
+			URLs []entityURL `json:"urls,omitempty"`
+		} `json:"description,omitempty"`
+	} `json:"entities,omitempty"`
 }
 
 type timelineInstruction struct {
 	Type    timelineInstructionType    `json:"type"`
 	Entries []timelineInstructionEntry `json:"entries,omitempty"`
 	Entry   *timelineInstructionEntry  `json:"entry,omitempty"`
+	// ModuleEntryID and ModuleItems are set for timelineAddToModule.
+	ModuleEntryID string                      `json:"moduleEntryId,omitempty"`
+	ModuleItems   []graphqlTimelineModuleItem `json:"moduleItems,omitempty"`
 }
 
 type timelineInstructionType string
 
 const (
-	timelineAddEntries timelineInstructionType = "TimelineAddEntries"
-	timelineClearCache                         = "TimelineClearCache"
-	timelinePinEntry                           = "TimelinePinEntry"
+	timelineAddEntries  timelineInstructionType = "TimelineAddEntries"
+	timelineClearCache                          = "TimelineClearCache"
+	timelinePinEntry                            = "TimelinePinEntry"
+	timelineAddToModule                         = "TimelineAddToModule"
 )
 
 type timelineInstructionEntry struct {
@@ -124,6 +173,113 @@ type graphqlTweet struct {
 	QuotedStatusResult *struct {
 		Result *graphqlObject `json:"result"`
 	} `json:"quoted_status_result"`
+	Views struct {
+		Count string `json:"count,omitempty"`
+	} `json:"views,omitempty"`
+	Card           *graphqlCard           `json:"card,omitempty"`
+	EditControl    *graphqlEditControl    `json:"edit_control,omitempty"`
+	BirdwatchPivot *graphqlBirdwatchPivot `json:"birdwatch_pivot,omitempty"`
+	NoteTweet      *struct {
+		NoteTweetResults struct {
+			Result *graphqlNoteTweet `json:"result"`
+		} `json:"note_tweet_results"`
+	} `json:"note_tweet,omitempty"`
+}
+
+type graphqlEditControl struct {
+	// Edited versions of a tweet only have the ID of the first version and
+	// a copy of its edit control.
+	InitialTweetID     string              `json:"initial_tweet_id,omitempty"`
+	EditControlInitial *graphqlEditControl `json:"edit_control_initial,omitempty"`
+
+	EditTweetIDs       []string `json:"edit_tweet_ids,omitempty"`
+	EditableUntilMsecs string   `json:"editable_until_msecs,omitempty"`
+	EditsRemaining     string   `json:"edits_remaining,omitempty"`
+	IsEditEligible     bool     `json:"is_edit_eligible,omitempty"`
+}
+
+func (e *graphqlEditControl) initial() *graphqlEditControl {
+	if e.EditControlInitial != nil {
+		return e.EditControlInitial
+	}
+	return e
+}
+
+func (e *graphqlEditControl) EditControls() *EditControls {
+	e = e.initial()
+	r := &EditControls{IsEditEligible: e.IsEditEligible}
+	r.EditsRemaining, _ = strconv.Atoi(e.EditsRemaining)
+	if ms, err := strconv.ParseInt(e.EditableUntilMsecs, 10, 64); err == nil {
+		r.EditableUntil = time.UnixMilli(ms).UTC().Format("2006-01-02T15:04:05.000Z07:00")
+	}
+	return r
+}
+
+// graphqlNoteTweet holds the full text of a long-form tweet.
+type graphqlNoteTweet struct {
+	ID        string   `json:"id,omitempty"`
+	Text      string   `json:"text"`
+	EntitySet entities `json:"entity_set"`
+	RichText  struct {
+		Tags []struct {
+			FromIndex int      `json:"from_index"`
+			ToIndex   int      `json:"to_index"`
+			Types     []string `json:"richtext_types"`
+		} `json:"richtext_tags"`
+	} `json:"richtext"`
+}
+
+func (t *graphqlTweet) noteTweet() *graphqlNoteTweet {
+	if t.NoteTweet == nil || t.NoteTweet.NoteTweetResults.Result == nil {
+		return nil
+	}
+	if t.NoteTweet.NoteTweetResults.Result.Text == "" {
+		return nil
+	}
+	return t.NoteTweet.NoteTweetResults.Result
+}
+
+// graphqlTweetWithVisibilityResults wraps tweets with limited reach.
+type graphqlTweetWithVisibilityResults struct {
+	Tweet *graphqlTweet `json:"tweet"`
+}
+
+// graphqlTweetTombstone is returned in place of deleted tweets, tweets from
+// suspended accounts, etc.
+type graphqlTweetTombstone struct {
+	Tombstone struct {
+		Text struct {
+			Text string `json:"text"`
+		} `json:"text"`
+	} `json:"tombstone"`
+}
+
+type graphqlTweetUnavailable struct {
+	Reason string `json:"reason"`
+}
+
+// parseTweetResult parses the result of a tweet query, unwrapping tweets
+// with visibility results. Tweets that can't be shown are returned as a
+// tombstone instead.
+func parseTweetResult(o *graphqlObject) (*graphqlTweet, *Tombstone, error) {
+	v, err := o.Parse()
+	if err != nil {
+		return nil, nil, err
+	}
+	switch v := v.(type) {
+	case *graphqlTweet:
+		return v, nil, nil
+	case *graphqlTweetWithVisibilityResults:
+		if v.Tweet == nil {
+			return nil, nil, fmt.Errorf("%s doesn't contain a tweet", o.TypeName)
+		}
+		return v.Tweet, nil, nil
+	case *graphqlTweetTombstone:
+		return nil, &Tombstone{Text: v.Tombstone.Text.Text}, nil
+	case *graphqlTweetUnavailable:
+		return nil, &Tombstone{Reason: v.Reason}, nil
+	}
+	return nil, nil, fmt.Errorf("tweet result has unexpected type %T", v)
 }
 
 type graphqlTweetCore struct {
@@ -140,30 +296,49 @@ func convertTimestamp(ts string) string {
 	return t.Format("2006-01-02T15:04:05.000Z07:00")
 }
 
-func (t *graphqlTweet) Tweet() twitter.Tweet {
-	r := twitter.Tweet{
-		TweetNoIncludes: twitter.TweetNoIncludes{
+func (t *graphqlTweet) metrics() *TweetMetrics {
+	// Not all tweets have view counts, e.g. the ones posted before views
+	// were introduced, so we leave it at zero if it's missing.
+	views, _ := strconv.Atoi(t.Views.Count)
+	return &TweetMetrics{
+		RetweetCount:    t.Legacy.Retweets,
+		ReplyCount:      t.Legacy.Replies,
+		LikeCount:       t.Legacy.Likes,
+		QuoteCount:      t.Legacy.Quotes,
+		BookmarkCount:   t.Legacy.Bookmarks,
+		ImpressionCount: views,
+	}
+}
+
+func (t *graphqlTweet) Tweet() Tweet {
+	r := Tweet{
+		TweetNoIncludes: TweetNoIncludes{
 			ID:              t.RestID,
 			Text:            t.Legacy.Text,
 			AuthorID:        t.Legacy.AuthorID,
 			ConversationID:  t.Legacy.ConversationID,
 			CreatedAt:       convertTimestamp(t.Legacy.CreatedAt),
 			InReplyToUserID: t.Legacy.InReplyToUserID,
+			PublicMetrics:   t.metrics(),
 		},
 	}
+	if t.BirdwatchPivot != nil {
+		r.CommunityNote = t.BirdwatchPivot.CommunityNote()
+	}
+	if t.EditControl != nil {
+		r.EditHistoryTweetIDs = t.EditControl.initial().EditTweetIDs
+		r.EditControls = t.EditControl.EditControls()
+	}
 	userIncluded := map[string]bool{}
 	mediaIncluded := map[string]bool{}
+	pollIncluded := map[string]bool{}
 	if t.Core.UserResults.Result != nil {
 		u, err := t.Core.UserResults.Result.Parse()
 		if err == nil {
 			u, ok := u.(*graphqlUser)
 			if ok {
 				if u.Legacy != nil {
-					r.Includes.Users = append(r.Includes.Users, twitter.TwitterUser{
-						ID:       u.RestID,
-						Name:     u.Legacy.Name,
-						Username: u.Legacy.ScreenName,
-					})
+					r.Includes.Users = append(r.Includes.Users, u.User())
 					userIncluded[u.RestID] = true
 				}
 			}
@@ -177,7 +352,7 @@ func (t *graphqlTweet) Tweet() twitter.Tweet {
 		r.ReferencedTweets = append(r.ReferencedTweets,
 			twitter.ReferencedTweet{Type: "quoted", ID: quoted})
 	}
-	addTweet := func(converted twitter.Tweet) {
+	addTweet := func(converted Tweet) {
 		r.Includes.Tweets = append(r.Includes.Tweets, converted.TweetNoIncludes)
 		r.Includes.Tweets = append(r.Includes.Tweets, converted.Includes.Tweets...)
 		for _, u := range converted.Includes.Users {
@@ -194,14 +369,20 @@ func (t *graphqlTweet) Tweet() twitter.Tweet {
 			r.Includes.Media = append(r.Includes.Media, m)
 			mediaIncluded[m.Key] = true
 		}
+		for _, p := range converted.Includes.Polls {
+			if pollIncluded[p.ID] {
+				continue
+			}
+			r.Includes.Polls = append(r.Includes.Polls, p)
+			pollIncluded[p.ID] = true
+		}
 	}
 
 	if rt := t.Legacy.RetweetedStatusResult; rt != nil {
 		if rt.Result != nil {
-			tw, err := rt.Result.Parse()
+			tw, _, err := parseTweetResult(rt.Result)
 			if err == nil {
-				tw, ok := tw.(*graphqlTweet)
-				if ok {
+				if tw != nil {
 					r.ReferencedTweets = append(r.ReferencedTweets,
 						twitter.ReferencedTweet{Type: "retweeted", ID: tw.RestID})
 					addTweet(tw.Tweet())
@@ -212,38 +393,77 @@ func (t *graphqlTweet) Tweet() twitter.Tweet {
 
 	if rt := t.QuotedStatusResult; rt != nil {
 		if rt.Result != nil {
-			tw, err := rt.Result.Parse()
+			tw, tombstone, err := parseTweetResult(rt.Result)
 			if err == nil {
-				tw, ok := tw.(*graphqlTweet)
-				if ok {
+				if tw != nil {
 					addTweet(tw.Tweet())
+				} else if t.Legacy.QuotedStatusID != "" {
+					addTweet(tombstoneTweet(t.Legacy.QuotedStatusID, tombstone))
 				}
 			}
 		}
 	}
 
-	if t.Legacy.Entities != nil {
-		for _, ue := range t.Legacy.Entities.URLs {
+	// Long tweets come with truncated legacy text, while the full text and
+	// entities for it are in the note tweet.
+	ents := t.Legacy.Entities
+	note := t.noteTweet()
+	if note != nil {
+		r.Text = note.Text
+		ents = &note.EntitySet
+	}
+	if note != nil {
+		for _, tag := range note.RichText.Tags {
+			r.RichText = append(r.RichText, RichTextTag{
+				TextEntity: textEntity([2]int{tag.FromIndex, tag.ToIndex}),
+				Types:      tag.Types,
+			})
+		}
+	}
+
+	if ents != nil {
+		for _, ue := range ents.URLs {
 			r.Entities.URLs = append(r.Entities.URLs, twitter.EntityURL{
-				TextEntity:  twitter.TextEntity{Start: uint16(ue.Indices[0]), End: uint16(ue.Indices[1])},
+				TextEntity:  textEntity(ue.Indices),
 				URL:         ue.URL,
 				ExpandedURL: ue.ExpandedURL,
 				DisplayURL:  ue.DisplayURL,
 			})
 		}
-		for _, he := range t.Legacy.Entities.Hashtags {
+		for _, he := range ents.Hashtags {
 			r.Entities.Hashtags = append(r.Entities.Hashtags, twitter.EntityHashtag{
-				TextEntity: twitter.TextEntity{Start: uint16(he.Indices[0]), End: uint16(he.Indices[1])},
+				TextEntity: textEntity(he.Indices),
 				Tag:        he.Text,
 			})
 		}
-		for _, me := range t.Legacy.Entities.UserMentions {
+		for _, se := range ents.Symbols {
+			r.Entities.Cashtags = append(r.Entities.Cashtags, EntityCashtag{
+				TextEntity: textEntity(se.Indices),
+				Tag:        se.Text,
+			})
+		}
+		for _, te := range ents.Timestamps {
+			r.Entities.Timestamps = append(r.Entities.Timestamps, EntityTimestamp{
+				TextEntity: textEntity(te.Indices),
+				Seconds:    te.Seconds,
+				Text:       te.Text,
+			})
+		}
+		for _, ae := range ents.Annotations {
+			r.Entities.Annotations = append(r.Entities.Annotations, EntityAnnotation{
+				TextEntity:     textEntity(ae.Indices),
+				Probability:    ae.Probability,
+				Type:           ae.Type,
+				NormalizedText: ae.NormalizedText,
+			})
+		}
+		for _, me := range ents.UserMentions {
 			r.Entities.Mentions = append(r.Entities.Mentions, twitter.EntityMention{
-				TextEntity: twitter.TextEntity{Start: uint16(me.Indices[0]), End: uint16(me.Indices[1])},
+				TextEntity: textEntity(me.Indices),
 				Username:   me.ScreenName,
 			})
 			if !userIncluded[me.ID] {
-				r.Includes.Users = append(r.Includes.Users, twitter.TwitterUser{
+				r.Includes.Users = append(r.Includes.Users, User{
 					ID:       me.ID,
 					Name:     me.Name,
 					Username: me.ScreenName,
@@ -256,41 +476,36 @@ func (t *graphqlTweet) Tweet() twitter.Tweet {
 	if t.Legacy.ExtendedEntities != nil {
 		for _, e := range t.Legacy.ExtendedEntities.Media {
 			r.Attachments.MediaKeys = append(r.Attachments.MediaKeys, e.MediaKey)
-			r.Entities.URLs = append(r.Entities.URLs, twitter.EntityURL{
-				TextEntity:  twitter.TextEntity{Start: uint16(e.Indices[0]), End: uint16(e.Indices[1])},
-				URL:         e.URL,
-				ExpandedURL: e.ExpandedURL,
-				DisplayURL:  e.DisplayURL,
-			})
+			// Note tweet text doesn't contain media links.
+			if note == nil {
+				r.Entities.URLs = append(r.Entities.URLs, twitter.EntityURL{
+					TextEntity:  textEntity(e.Indices),
+					URL:         e.URL,
+					ExpandedURL: e.ExpandedURL,
+					DisplayURL:  e.DisplayURL,
+				})
+			}
 
 			if mediaIncluded[e.MediaKey] {
 				continue
 			}
-			m := twitter.Media{
-				Type: e.Type,
-				Key:  e.MediaKey,
-			}
-			switch e.Type {
-			case "photo":
-				m.URL = e.MediaURLHTTPS
-			default:
-				m.PreviewURL = e.MediaURLHTTPS
-			}
-			if e.VideoInfo != nil {
-				_ = json.Unmarshal(e.VideoInfo.Variants, &m.Variants)
-				for i, v := range m.Variants {
-					if b, ok := v["bitrate"]; ok {
-						v["bit_rate"] = b
-						delete(v, "bitrate")
-						m.Variants[i] = v
-					}
-				}
-			}
+			m := e.Media()
 			r.Includes.Media = append(r.Includes.Media, m)
 			mediaIncluded[m.Key] = true
 		}
 	}
 
+	if t.Card != nil {
+		if p := t.Card.Poll(); p != nil {
+			r.Attachments.PollIDs = append(r.Attachments.PollIDs, p.ID)
+			if !pollIncluded[p.ID] {
+				r.Includes.Polls = append(r.Includes.Polls, *p)
+				pollIncluded[p.ID] = true
+			}
+		}
+		r.Card = t.Card.Card()
+	}
+
 	return r
 }
 
@@ -304,6 +519,7 @@ type graphqlTweetLegacy struct {
 	Replies               int       `json:"reply_count,omitempty"`
 	Retweets              int       `json:"retweet_count,omitempty"`
 	Quotes                int       `json:"quote_count,omitempty"`
+	Bookmarks             int       `json:"bookmark_count,omitempty"`
 	InReplyToUserID       string    `json:"in_reply_to_user_id_str"`
 	InReplyToStatusID     string    `json:"in_reply_to_status_id_str"`
 	QuotedStatusID        string    `json:"quoted_status_id_str"`
@@ -326,7 +542,9 @@ type entities struct {
 	URLs         []entityURL         `json:"urls"`
 	UserMentions []entityUserMention `json:"user_mentions"`
 	Hashtags     []entityHashtag     `json:"hashtags"`
-	//Symbols      []entitySymbol      `json:"symbols"`
+	Symbols      []entityHashtag     `json:"symbols"`
+	Timestamps   []entityTimestamp   `json:"timestamps"`
+	Annotations  []entityAnnotation  `json:"annotations"`
 }
 
 type entityMedia struct {
@@ -338,16 +556,71 @@ type entityMedia struct {
 	Indices              [2]int `json:"indices"`
 	MediaURLHTTPS        string `json:"media_url_https"`
 	MediaKey             string `json:"media_key"`
-	ExtMediaAvailability struct {
+	ExtAltText           string `json:"ext_alt_text,omitempty"`
+	ExtMediaAvailability *struct {
 		Status string `json:"status"`
-	} `json:"ext_media_availability"`
-	Sizes        struct{}   `json:"sizes"`
-	OriginalInfo struct{}   `json:"original_info"`
+		Reason string `json:"reason,omitempty"`
+	} `json:"ext_media_availability,omitempty"`
+	Sizes struct {
+		Large *mediaSize `json:"large,omitempty"`
+	} `json:"sizes"`
+	OriginalInfo *mediaSize `json:"original_info,omitempty"`
 	VideoInfo    *videoInfo `json:"video_info"`
+	MediaStats   *struct {
+		ViewCount int `json:"viewCount"`
+	} `json:"mediaStats,omitempty"`
+}
+
+type mediaSize struct {
+	// Sizes use "w" and "h", while original_info uses "width" and "height".
+	W      int `json:"w,omitempty"`
+	H      int `json:"h,omitempty"`
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
 }
 
 type videoInfo struct {
-	Variants json.RawMessage `json:"variants"`
+	DurationMillis int             `json:"duration_millis,omitempty"`
+	Variants       json.RawMessage `json:"variants"`
+}
+
+func (e *entityMedia) Media() Media {
+	m := Media{
+		Media: twitter.Media{
+			Type:    e.Type,
+			Key:     e.MediaKey,
+			AltText: e.ExtAltText,
+		},
+	}
+	switch e.Type {
+	case "photo":
+		m.URL = e.MediaURLHTTPS
+	default:
+		m.PreviewURL = e.MediaURLHTTPS
+	}
+	if o := e.OriginalInfo; o != nil && o.Width > 0 {
+		m.Width, m.Height = o.Width, o.Height
+	} else if l := e.Sizes.Large; l != nil {
+		m.Width, m.Height = l.W, l.H
+	}
+	if e.VideoInfo != nil {
+		m.DurationMS = e.VideoInfo.DurationMillis
+		_ = json.Unmarshal(e.VideoInfo.Variants, &m.Variants)
+		for i, v := range m.Variants {
+			if b, ok := v["bitrate"]; ok {
+				v["bit_rate"] = b
+				delete(v, "bitrate")
+				m.Variants[i] = v
+			}
+		}
+	}
+	if e.MediaStats != nil {
+		m.PublicMetrics = &MediaMetrics{ViewCount: e.MediaStats.ViewCount}
+	}
+	if a := e.ExtMediaAvailability; a != nil && a.Status != "" {
+		m.Availability = &MediaAvailability{Status: a.Status, Reason: a.Reason}
+	}
+	return m
 }
 
 type entityURL struct {
@@ -362,6 +635,19 @@ type entityHashtag struct {
 	Indices [2]int `json:"indices"`
 }
 
+type entityTimestamp struct {
+	Indices [2]int `json:"indices"`
+	Seconds int    `json:"seconds"`
+	Text    string `json:"text"`
+}
+
+type entityAnnotation struct {
+	Indices        [2]int  `json:"indices"`
+	Probability    float64 `json:"probability"`
+	Type           string  `json:"type"`
+	NormalizedText string  `json:"normalized_text"`
+}
+
 type entityUserMention struct {
 	Indices    [2]int `json:"indices"`
 	Name       string `json:"name"`
@@ -370,9 +656,12 @@ type entityUserMention struct {
 }
 
 type graphqlTimelineModule struct {
-	Items []struct {
-		Item struct {
-			ItemContent *graphqlObject `json:"itemContent"`
-		} `json:"item"`
-	} `json:"items"`
+	Items []graphqlTimelineModuleItem `json:"items"`
+}
+
+type graphqlTimelineModuleItem struct {
+	EntryID string `json:"entryId"`
+	Item    struct {
+		ItemContent *graphqlObject `json:"itemContent"`
+	} `json:"item"`
 }
@@ -0,0 +1,401 @@
+package pwitter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Ukraine-DAO/twitter-threads/twitter"
+	"github.com/google/go-cmp/cmp"
+)
+
+func parseTestTweet(t *testing.T, s string) Tweet {
+	t.Helper()
+	o := &graphqlObject{}
+	if err := json.Unmarshal([]byte(s), o); err != nil {
+		t.Fatalf("unmarshaling test tweet: %s", err)
+	}
+	v, err := o.Parse()
+	if err != nil {
+		t.Fatalf("parsing test tweet: %s", err)
+	}
+	tw, ok := v.(*graphqlTweet)
+	if !ok {
+		t.Fatalf("test tweet has unexpected type %T", v)
+	}
+	return tw.Tweet()
+}
+
+func TestTweetPublicMetrics(t *testing.T) {
+	tw := parseTestTweet(t, `{
+		"__typename": "Tweet",
+		"rest_id": "1650000000000000000",
+		"views": {"count": "12345", "state": "EnabledWithCount"},
+		"legacy": {
+			"id_str": "1650000000000000000",
+			"full_text": "Hello",
+			"user_id_str": "783214",
+			"favorite_count": 10,
+			"reply_count": 2,
+			"retweet_count": 3,
+			"quote_count": 1,
+			"bookmark_count": 4
+		}
+	}`)
+	want := &TweetMetrics{
+		RetweetCount:    3,
+		ReplyCount:      2,
+		LikeCount:       10,
+		QuoteCount:      1,
+		BookmarkCount:   4,
+		ImpressionCount: 12345,
+	}
+	if diff := cmp.Diff(want, tw.PublicMetrics); diff != "" {
+		t.Errorf("unexpected public_metrics (-want +got):\n%s", diff)
+	}
+}
+
+func TestTweetNoteTweet(t *testing.T) {
+	tw := parseTestTweet(t, `{
+		"__typename": "Tweet",
+		"rest_id": "1650000000000000001",
+		"note_tweet": {
+			"is_expandable": true,
+			"note_tweet_results": {
+				"result": {
+					"id": "Tm90ZVR3ZWV0OjE2NTAwMDAwMDAwMDAwMDAwMDE=",
+					"text": "A very long text about #golang by @TwitterDev that goes on and on",
+					"entity_set": {
+						"hashtags": [{"indices": [23, 30], "text": "golang"}],
+						"user_mentions": [{"indices": [34, 45], "id_str": "2244994945", "name": "Twitter Dev", "screen_name": "TwitterDev"}],
+						"urls": []
+					},
+					"richtext": {"richtext_tags": [{"from_index": 2, "to_index": 11, "richtext_types": ["Bold"]}]}
+				}
+			}
+		},
+		"legacy": {
+			"id_str": "1650000000000000001",
+			"full_text": "A very long text… https://t.co/note https://t.co/media",
+			"user_id_str": "783214",
+			"entities": {"urls": [{"indices": [18, 35], "url": "https://t.co/note", "expanded_url": "https://twitter.com/i/web/status/1650000000000000001", "display_url": "twitter.com/i/web/status/1…"}]},
+			"extended_entities": {"media": [{"type": "photo", "media_key": "3_1", "indices": [36, 54], "url": "https://t.co/media", "media_url_https": "https://pbs.twimg.com/media/a.jpg"}]}
+		}
+	}`)
+
+	if want := "A very long text about #golang by @TwitterDev that goes on and on"; tw.Text != want {
+		t.Errorf("got text %q, want %q", tw.Text, want)
+	}
+	wantEntities := Entities{
+		Hashtags: []twitter.EntityHashtag{{TextEntity: twitter.TextEntity{Start: 23, End: 30}, Tag: "golang"}},
+		Mentions: []twitter.EntityMention{{TextEntity: twitter.TextEntity{Start: 34, End: 45}, Username: "TwitterDev"}},
+	}
+	if diff := cmp.Diff(wantEntities, tw.Entities); diff != "" {
+		t.Errorf("unexpected entities (-want +got):\n%s", diff)
+	}
+	wantRichText := []RichTextTag{{TextEntity: twitter.TextEntity{Start: 2, End: 11}, Types: []string{"Bold"}}}
+	if diff := cmp.Diff(wantRichText, tw.RichText); diff != "" {
+		t.Errorf("unexpected richtext tags (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"3_1"}, tw.Attachments.MediaKeys); diff != "" {
+		t.Errorf("unexpected media keys (-want +got):\n%s", diff)
+	}
+}
+
+func TestTweetFromItemContent(t *testing.T) {
+	cases := []struct {
+		desc    string
+		entryID string
+		content string
+		want    *Tweet
+	}{
+		{
+			desc:    "visibility results",
+			entryID: "tweet-1650000000000000002",
+			content: `{
+				"__typename": "TimelineTweet",
+				"tweet_results": {"result": {
+					"__typename": "TweetWithVisibilityResults",
+					"tweet": {
+						"rest_id": "1650000000000000002",
+						"legacy": {"id_str": "1650000000000000002", "full_text": "Limited", "user_id_str": "783214"}
+					}
+				}}
+			}`,
+			want: &Tweet{TweetNoIncludes: TweetNoIncludes{
+				ID:            "1650000000000000002",
+				Text:          "Limited",
+				AuthorID:      "783214",
+				PublicMetrics: &TweetMetrics{},
+			}},
+		},
+		{
+			desc:    "tombstone",
+			entryID: "conversationthread-1650000000000000000-tweet-1650000000000000003",
+			content: `{
+				"__typename": "TimelineTweet",
+				"tweet_results": {"result": {
+					"__typename": "TweetTombstone",
+					"tombstone": {"text": {"text": "This Tweet was deleted by the Tweet author."}}
+				}}
+			}`,
+			want: &Tweet{TweetNoIncludes: TweetNoIncludes{
+				ID:        "1650000000000000003",
+				Tombstone: &Tombstone{Text: "This Tweet was deleted by the Tweet author."},
+			}},
+		},
+		{
+			desc:    "unavailable",
+			entryID: "tweet-1650000000000000004",
+			content: `{
+				"__typename": "TimelineTweet",
+				"tweet_results": {"result": {"__typename": "TweetUnavailable", "reason": "Protected"}}
+			}`,
+			want: &Tweet{TweetNoIncludes: TweetNoIncludes{
+				ID:        "1650000000000000004",
+				Tombstone: &Tombstone{Reason: "Protected"},
+			}},
+		},
+		{
+			desc:    "not a tweet",
+			entryID: "who-to-follow-1",
+			content: `{"__typename": "TimelineUser"}`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			o := &graphqlObject{}
+			if err := json.Unmarshal([]byte(tc.content), o); err != nil {
+				t.Fatalf("unmarshaling item content: %s", err)
+			}
+			got := tweetFromItemContent(context.Background(), tc.entryID, o)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected tweet (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTweetPoll(t *testing.T) {
+	tw := parseTestTweet(t, `{
+		"__typename": "Tweet",
+		"rest_id": "1650000000000000005",
+		"card": {
+			"rest_id": "card://1650000000000000006",
+			"legacy": {
+				"name": "poll2choice_text_only",
+				"url": "card://1650000000000000006",
+				"binding_values": [
+					{"key": "choice1_label", "value": {"string_value": "Yes", "type": "STRING"}},
+					{"key": "choice2_label", "value": {"string_value": "No", "type": "STRING"}},
+					{"key": "choice1_count", "value": {"string_value": "42", "type": "STRING"}},
+					{"key": "choice2_count", "value": {"string_value": "7", "type": "STRING"}},
+					{"key": "duration_minutes", "value": {"string_value": "1440", "type": "STRING"}},
+					{"key": "end_datetime_utc", "value": {"string_value": "2023-04-21T12:00:00Z", "type": "STRING"}},
+					{"key": "counts_are_final", "value": {"boolean_value": true, "type": "BOOLEAN"}}
+				]
+			}
+		},
+		"legacy": {"id_str": "1650000000000000005", "full_text": "Do you like polls?", "user_id_str": "783214"}
+	}`)
+	if diff := cmp.Diff([]string{"1650000000000000006"}, tw.Attachments.PollIDs); diff != "" {
+		t.Errorf("unexpected poll_ids (-want +got):\n%s", diff)
+	}
+	want := []Poll{{
+		ID: "1650000000000000006",
+		Options: []PollOption{
+			{Position: 1, Label: "Yes", Votes: 42},
+			{Position: 2, Label: "No", Votes: 7},
+		},
+		DurationMinutes: 1440,
+		EndDatetime:     "2023-04-21T12:00:00Z",
+		VotingStatus:    "closed",
+	}}
+	if diff := cmp.Diff(want, tw.Includes.Polls); diff != "" {
+		t.Errorf("unexpected polls (-want +got):\n%s", diff)
+	}
+	if tw.Card != nil {
+		t.Errorf("poll is also returned as a card: %+v", tw.Card)
+	}
+}
+
+func TestPollVotingStatus(t *testing.T) {
+	cases := []struct {
+		name   string
+		end    string
+		values string
+		want   string
+	}{
+		{
+			name:   "final counts",
+			values: `{"key": "counts_are_final", "value": {"boolean_value": true, "type": "BOOLEAN"}}`,
+			want:   "closed",
+		},
+		{
+			name:   "updated before the end",
+			values: `{"key": "last_updated_datetime_utc", "value": {"string_value": "2023-04-21T11:59:00Z", "type": "STRING"}}`,
+			want:   "open",
+		},
+		{
+			name:   "updated after the end",
+			values: `{"key": "last_updated_datetime_utc", "value": {"string_value": "2023-04-21T12:00:00Z", "type": "STRING"}}`,
+			want:   "closed",
+		},
+		{
+			name:   "no update time after the end",
+			values: `{"key": "counts_are_final", "value": {"boolean_value": false, "type": "BOOLEAN"}}`,
+			want:   "closed",
+		},
+		{
+			name: "no update time before the end",
+			end:  "2999-01-01T00:00:00Z",
+			want: "open",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			end := tc.end
+			if end == "" {
+				end = "2023-04-21T12:00:00Z"
+			}
+			values := `{"key": "end_datetime_utc", "value": {"string_value": "` + end + `", "type": "STRING"}}`
+			if tc.values != "" {
+				values += "," + tc.values
+			}
+			c := &graphqlCard{}
+			err := json.Unmarshal([]byte(`{"legacy": {"name": "poll2choice_text_only", "url": "card://1", "binding_values": [`+values+`]}}`), c)
+			if err != nil {
+				t.Fatalf("unmarshaling card: %s", err)
+			}
+			if got := c.Poll().VotingStatus; got != tc.want {
+				t.Errorf("got voting status %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTweetLinkCard(t *testing.T) {
+	tw := parseTestTweet(t, `{
+		"__typename": "Tweet",
+		"rest_id": "1650000000000000007",
+		"card": {
+			"rest_id": "https://t.co/abc",
+			"legacy": {
+				"name": "summary_large_image",
+				"url": "https://t.co/abc",
+				"binding_values": [
+					{"key": "title", "value": {"string_value": "Example", "type": "STRING"}},
+					{"key": "description", "value": {"string_value": "An example page", "type": "STRING"}},
+					{"key": "vanity_url", "value": {"string_value": "example.com", "type": "STRING"}},
+					{"key": "card_url", "value": {"string_value": "https://t.co/abc", "type": "STRING"}},
+					{"key": "thumbnail_image", "value": {"image_value": {"url": "https://pbs.twimg.com/card_img/1/small", "width": 144, "height": 72}, "type": "IMAGE"}},
+					{"key": "thumbnail_image_large", "value": {"image_value": {"url": "https://pbs.twimg.com/card_img/1/large", "width": 800, "height": 400}, "type": "IMAGE"}}
+				]
+			}
+		},
+		"legacy": {"id_str": "1650000000000000007", "full_text": "Look https://t.co/abc", "user_id_str": "783214"}
+	}`)
+	want := &Card{
+		Name:         "summary_large_image",
+		URL:          "https://t.co/abc",
+		Title:        "Example",
+		Description:  "An example page",
+		Domain:       "example.com",
+		ThumbnailURL: "https://pbs.twimg.com/card_img/1/large",
+	}
+	if diff := cmp.Diff(want, tw.Card); diff != "" {
+		t.Errorf("unexpected card (-want +got):\n%s", diff)
+	}
+	if len(tw.Includes.Polls) > 0 || len(tw.Attachments.PollIDs) > 0 {
+		t.Errorf("link card is returned as a poll")
+	}
+}
+
+func TestTweetMedia(t *testing.T) {
+	tw := parseTestTweet(t, `{
+		"__typename": "Tweet",
+		"rest_id": "1650000000000000008",
+		"legacy": {
+			"id_str": "1650000000000000008",
+			"full_text": "https://t.co/p https://t.co/v",
+			"user_id_str": "783214",
+			"extended_entities": {"media": [
+				{
+					"type": "photo", "media_key": "3_1", "indices": [0, 14], "url": "https://t.co/p",
+					"media_url_https": "https://pbs.twimg.com/media/a.jpg",
+					"ext_alt_text": "A cat on a keyboard",
+					"ext_media_availability": {"status": "Available"},
+					"sizes": {"large": {"w": 2048, "h": 1536, "resize": "fit"}},
+					"original_info": {"width": 4032, "height": 3024}
+				},
+				{
+					"type": "video", "media_key": "7_2", "indices": [15, 29], "url": "https://t.co/v",
+					"media_url_https": "https://pbs.twimg.com/ext_tw_video_thumb/2/pu/img/b.jpg",
+					"ext_media_availability": {"status": "Unavailable", "reason": "Dmcaed"},
+					"sizes": {"large": {"w": 1280, "h": 720, "resize": "fit"}},
+					"mediaStats": {"viewCount": 1000},
+					"video_info": {
+						"duration_millis": 15015,
+						"variants": [{"bitrate": 832000, "content_type": "video/mp4", "url": "https://video.twimg.com/b.mp4"}]
+					}
+				}
+			]}
+		}
+	}`)
+	want := []Media{
+		{
+			Media: twitter.Media{
+				Type:    "photo",
+				Key:     "3_1",
+				URL:     "https://pbs.twimg.com/media/a.jpg",
+				AltText: "A cat on a keyboard",
+			},
+			Width:        4032,
+			Height:       3024,
+			Availability: &MediaAvailability{Status: "Available"},
+		},
+		{
+			Media: twitter.Media{
+				Type:       "video",
+				Key:        "7_2",
+				PreviewURL: "https://pbs.twimg.com/ext_tw_video_thumb/2/pu/img/b.jpg",
+				Variants: []map[string]interface{}{
+					{"bit_rate": float64(832000), "content_type": "video/mp4", "url": "https://video.twimg.com/b.mp4"},
+				},
+			},
+			Width:         1280,
+			Height:        720,
+			DurationMS:    15015,
+			PublicMetrics: &MediaMetrics{ViewCount: 1000},
+			Availability:  &MediaAvailability{Status: "Unavailable", Reason: "Dmcaed"},
+		},
+	}
+	if diff := cmp.Diff(want, tw.Includes.Media); diff != "" {
+		t.Errorf("unexpected media (-want +got):\n%s", diff)
+	}
+}
+
+func TestTweetEntities(t *testing.T) {
+	tw := parseTestTweet(t, `{
+		"__typename": "Tweet",
+		"rest_id": "1650000000000000009",
+		"legacy": {
+			"id_str": "1650000000000000009",
+			"full_text": "$TSLA is up, see 1:23 in the video",
+			"user_id_str": "783214",
+			"entities": {
+				"symbols": [{"indices": [0, 5], "text": "TSLA"}],
+				"timestamps": [{"indices": [17, 21], "seconds": 83, "text": "1:23"}],
+				"annotations": [{"indices": [1, 5], "probability": 0.9, "type": "Organization", "normalized_text": "Tesla"}]
+			}
+		}
+	}`)
+	want := Entities{
+		Cashtags:    []EntityCashtag{{TextEntity: twitter.TextEntity{Start: 0, End: 5}, Tag: "TSLA"}},
+		Timestamps:  []EntityTimestamp{{TextEntity: twitter.TextEntity{Start: 17, End: 21}, Seconds: 83, Text: "1:23"}},
+		Annotations: []EntityAnnotation{{TextEntity: twitter.TextEntity{Start: 1, End: 5}, Probability: 0.9, Type: "Organization", NormalizedText: "Tesla"}},
+	}
+	if diff := cmp.Diff(want, tw.Entities); diff != "" {
+		t.Errorf("unexpected entities (-want +got):\n%s", diff)
+	}
+}
@@ -74,6 +74,38 @@ func TestTweetDetail(t *testing.T) {
 	}
 }
 
+func TestConversation(t *testing.T) {
+	out := zerolog.NewConsoleWriter(zerolog.ConsoleTestWriter(t))
+	log := zerolog.New(out).Level(zerolog.DebugLevel)
+	ctx := log.WithContext(context.Background())
+	client := createClient(ctx, t)
+	r, err := client.Conversation(ctx, "1580661436132757506")
+	if err != nil {
+		t.Fatalf("Conversation returned error: %s", err)
+	}
+	if r.Focal.Tweet.ID != "1580661436132757506" {
+		t.Errorf("unexpected focal tweet: %q", r.Focal.Tweet.ID)
+	}
+	t.Logf("%d replies to the focal tweet", len(r.Focal.Replies))
+}
+
+func TestUserByRestID(t *testing.T) {
+	out := zerolog.NewConsoleWriter(zerolog.ConsoleTestWriter(t))
+	log := zerolog.New(out).Level(zerolog.DebugLevel)
+	ctx := log.WithContext(context.Background())
+	client := createClient(ctx, t)
+	r, err := client.UserByRestID(ctx, testAccountID)
+	if err != nil {
+		t.Fatalf("UserByRestID returned error: %s", err)
+	}
+	if r.User.ID != testAccountID || r.User.Username != "Twitter" {
+		t.Errorf("unexpected user: %+v", r.User)
+	}
+	if r.User.PublicMetrics.FollowersCount == 0 {
+		t.Errorf("user has no followers: %+v", r.User)
+	}
+}
+
 func TestTweetContent(t *testing.T) {
 	out := zerolog.NewConsoleWriter(zerolog.ConsoleTestWriter(t))
 	log := zerolog.New(out).Level(zerolog.DebugLevel)
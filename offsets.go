@@ -0,0 +1,156 @@
+package pwitter
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Ukraine-DAO/twitter-threads/twitter"
+)
+
+// Entity offsets count characters (code points) in the text they point into.
+// Tweet text is HTML-escaped, same as in API v2, and offsets are counted over
+// the escaped text. When the text gets unescaped, offsets have to be adjusted
+// to match.
+
+// Legacy API escapes only these characters in tweet text.
+var (
+	htmlEscapes = map[string]rune{
+		"&amp;": '&',
+		"&lt;":  '<',
+		"&gt;":  '>',
+	}
+	htmlUnescaper = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">")
+	htmlEscaper   = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+)
+
+// unescapeHTML replaces HTML entities in tweet text with the characters they
+// stand for. Unlike html.UnescapeString it only handles the entities that
+// Twitter produces, so that it agrees with unescapeOffsets.
+func unescapeHTML(s string) string {
+	return htmlUnescaper.Replace(s)
+}
+
+// escapeHTML reverses unescapeHTML.
+func escapeHTML(s string) string {
+	return htmlEscaper.Replace(s)
+}
+
+// textEntity converts legacy indices into a text entity. The indices are
+// kept as they are: legacy API counts code points over the escaped text, and
+// so does API v2, so there's nothing to convert unless the text gets
+// unescaped. In particular, they are not UTF-16 offsets, and characters
+// outside of the BMP, like most emoji, count as one.
+func textEntity(indices [2]int) twitter.TextEntity {
+	return twitter.TextEntity{Start: uint16(indices[0]), End: uint16(indices[1])}
+}
+
+// textOffsets maps character positions in one form of a text onto the
+// positions in another one.
+type textOffsets []uint16
+
+// unescapeOffsets maps positions in HTML-escaped text onto the positions in
+// the same text after unescapeHTML.
+func unescapeOffsets(text string) textOffsets {
+	r := make(textOffsets, 0, utf8.RuneCountInString(text)+1)
+	var pos uint16
+	for i := 0; i < len(text); {
+		if n, ok := htmlEscapeAt(text[i:]); ok {
+			// All characters of the escape sequence map onto the
+			// single character it stands for.
+			for j := 0; j < n; j++ {
+				r = append(r, pos)
+			}
+			pos++
+			i += n
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		r = append(r, pos)
+		pos++
+		i += size
+	}
+	return append(r, pos)
+}
+
+// escapeOffsets maps positions in unescaped text onto the positions in the
+// same text after escapeHTML.
+func escapeOffsets(text string) textOffsets {
+	r := make(textOffsets, 0, utf8.RuneCountInString(text)+1)
+	var pos uint16
+	for _, c := range text {
+		r = append(r, pos)
+		switch c {
+		case '&':
+			pos += uint16(len("&amp;"))
+		case '<', '>':
+			pos += uint16(len("&lt;"))
+		default:
+			pos++
+		}
+	}
+	return append(r, pos)
+}
+
+func htmlEscapeAt(s string) (int, bool) {
+	if !strings.HasPrefix(s, "&") {
+		return 0, false
+	}
+	for esc := range htmlEscapes {
+		if strings.HasPrefix(s, esc) {
+			return len(esc), true
+		}
+	}
+	return 0, false
+}
+
+// offset converts a single position. Positions past the end of the text are
+// clamped to its length.
+func (o textOffsets) offset(i uint16) uint16 {
+	if int(i) >= len(o) {
+		return o[len(o)-1]
+	}
+	return o[i]
+}
+
+func (o textOffsets) entity(e twitter.TextEntity) twitter.TextEntity {
+	return twitter.TextEntity{Start: o.offset(e.Start), End: o.offset(e.End)}
+}
+
+// remap returns a copy of entities with positions converted by o.
+func (e *Entities) remap(o textOffsets) Entities {
+	r := Entities{}
+	for _, u := range e.URLs {
+		u.TextEntity = o.entity(u.TextEntity)
+		r.URLs = append(r.URLs, u)
+	}
+	for _, h := range e.Hashtags {
+		h.TextEntity = o.entity(h.TextEntity)
+		r.Hashtags = append(r.Hashtags, h)
+	}
+	for _, m := range e.Mentions {
+		m.TextEntity = o.entity(m.TextEntity)
+		r.Mentions = append(r.Mentions, m)
+	}
+	for _, c := range e.Cashtags {
+		c.TextEntity = o.entity(c.TextEntity)
+		r.Cashtags = append(r.Cashtags, c)
+	}
+	for _, a := range e.Annotations {
+		a.TextEntity = o.entity(a.TextEntity)
+		r.Annotations = append(r.Annotations, a)
+	}
+	for _, t := range e.Timestamps {
+		t.TextEntity = o.entity(t.TextEntity)
+		r.Timestamps = append(r.Timestamps, t)
+	}
+	return r
+}
+
+func remapRichText(tags []RichTextTag, o textOffsets) []RichTextTag {
+	var r []RichTextTag
+	for _, t := range tags {
+		t.TextEntity = o.entity(t.TextEntity)
+		r = append(r, t)
+	}
+	return r
+}
@@ -0,0 +1,119 @@
+package pwitter
+
+import (
+	"testing"
+
+	"github.com/Ukraine-DAO/twitter-threads/twitter"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnescapeOffsets(t *testing.T) {
+	cases := []struct {
+		desc   string
+		text   string
+		entity twitter.TextEntity
+		want   twitter.TextEntity
+		// lossy is set if escaping the unescaped text doesn't give the
+		// original text or offsets.
+		lossy bool
+	}{
+		{"ascii", "plain #go", twitter.TextEntity{Start: 6, End: 9}, twitter.TextEntity{Start: 6, End: 9}, false},
+		{"emoji", "😀 #go", twitter.TextEntity{Start: 2, End: 5}, twitter.TextEntity{Start: 2, End: 5}, false},
+		{"escaped ampersand", "a &amp; b #go", twitter.TextEntity{Start: 10, End: 13}, twitter.TextEntity{Start: 6, End: 9}, false},
+		{"zwj sequence", "👩‍👩‍👧 &amp; @user", twitter.TextEntity{Start: 12, End: 17}, twitter.TextEntity{Start: 8, End: 13}, false},
+		{"flag and escape", "🇺🇦&lt;3 #x", twitter.TextEntity{Start: 8, End: 10}, twitter.TextEntity{Start: 5, End: 7}, false},
+		{"entity over escape", "&gt;&gt; x", twitter.TextEntity{Start: 0, End: 8}, twitter.TextEntity{Start: 0, End: 2}, false},
+		{"ampersand without escape", "R&D #go", twitter.TextEntity{Start: 4, End: 7}, twitter.TextEntity{Start: 4, End: 7}, true},
+		{"past the end", "ab", twitter.TextEntity{Start: 0, End: 5}, twitter.TextEntity{Start: 0, End: 2}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := unescapeOffsets(tc.text).entity(tc.entity)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected entity for %q %v (-want +got):\n%s", tc.text, tc.entity, diff)
+			}
+			if tc.lossy {
+				return
+			}
+			// Converting back must give the original offsets.
+			back := escapeOffsets(unescapeHTML(tc.text)).entity(got)
+			if diff := cmp.Diff(tc.entity, back); diff != "" {
+				t.Errorf("escaping doesn't restore the offsets for %q (-want +got):\n%s", tc.text, diff)
+			}
+		})
+	}
+}
+
+func TestUnescapeHTML(t *testing.T) {
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"a &amp; b", "a & b"},
+		{"&lt;3 &gt;", "<3 >"},
+		{"&amp;lt;", "&lt;"},
+		{"&quot;quoted&quot;", "&quot;quoted&quot;"},
+		{"🇺🇦 &amp; 😀", "🇺🇦 & 😀"},
+	}
+	for _, tc := range cases {
+		if got := unescapeHTML(tc.text); got != tc.want {
+			t.Errorf("unescapeHTML(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+		if tc.text != "&quot;quoted&quot;" {
+			if got := escapeHTML(tc.want); got != tc.text {
+				t.Errorf("escapeHTML(%q) = %q, want %q", tc.want, got, tc.text)
+			}
+		}
+	}
+}
+
+func TestTweetEntityOffsets(t *testing.T) {
+	const text = "🇺🇦 Tom &amp; Jerry 😀 #cartoons @TwitterDev https://t.co/x"
+	tw := parseTestTweet(t, `{
+		"__typename": "Tweet",
+		"rest_id": "1650000000000000010",
+		"legacy": {
+			"id_str": "1650000000000000010",
+			"full_text": "`+text+`",
+			"user_id_str": "783214",
+			"entities": {
+				"hashtags": [{"indices": [21, 30], "text": "cartoons"}],
+				"user_mentions": [{"indices": [31, 42], "id_str": "2244994945", "name": "Twitter Dev", "screen_name": "TwitterDev"}],
+				"urls": [{"indices": [43, 57], "url": "https://t.co/x", "expanded_url": "https://example.com", "display_url": "example.com"}]
+			}
+		}
+	}`)
+	escaped := twitter.Entities{
+		URLs:     []twitter.EntityURL{{TextEntity: twitter.TextEntity{Start: 43, End: 57}, URL: "https://t.co/x", ExpandedURL: "https://example.com", DisplayURL: "example.com"}},
+		Hashtags: []twitter.EntityHashtag{{TextEntity: twitter.TextEntity{Start: 21, End: 30}, Tag: "cartoons"}},
+		Mentions: []twitter.EntityMention{{TextEntity: twitter.TextEntity{Start: 31, End: 42}, Username: "TwitterDev"}},
+	}
+	if diff := cmp.Diff(escaped, tw.Entities.Twitter()); diff != "" {
+		t.Errorf("unexpected entities (-want +got):\n%s", diff)
+	}
+
+	tw.unescapeHTML()
+	if want := "🇺🇦 Tom & Jerry 😀 #cartoons @TwitterDev https://t.co/x"; tw.Text != want {
+		t.Errorf("unescaped text is %q, want %q", tw.Text, want)
+	}
+	runes := []rune(tw.Text)
+	for _, h := range tw.Entities.Hashtags {
+		if got := string(runes[h.Start:h.End]); got != "#cartoons" {
+			t.Errorf("hashtag offsets point at %q in unescaped text", got)
+		}
+	}
+	for _, u := range tw.Entities.URLs {
+		if got := string(runes[u.Start:u.End]); got != "https://t.co/x" {
+			t.Errorf("url offsets point at %q in unescaped text", got)
+		}
+	}
+
+	// twitter-threads gets the escaped text with the original offsets.
+	got := tw.Twitter()
+	if got.Text != text {
+		t.Errorf("converted text is %q, want %q", got.Text, text)
+	}
+	if diff := cmp.Diff(escaped, got.Entities); diff != "" {
+		t.Errorf("unexpected converted entities (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,206 @@
+package pwitter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// PoolStrategy selects which identity of a PoolAuthorizer is used for the
+// next request.
+type PoolStrategy int
+
+const (
+	// RoundRobin uses available identities in turn.
+	RoundRobin PoolStrategy = iota
+	// LeastRecentlyThrottled uses the available identity that was
+	// throttled the longest time ago.
+	LeastRecentlyThrottled
+)
+
+// defaultThrottleDuration is how long an identity is kept out of rotation
+// if the server doesn't say when its rate limit resets.
+const defaultThrottleDuration = 15 * time.Minute
+
+type poolMember struct {
+	auth           *AnonymousAuthorizer
+	throttledAt    time.Time
+	throttledUntil time.Time
+}
+
+// PoolAuthorizer spreads requests across several guest identities. An
+// identity is taken out of rotation when a request made with it gets
+// throttled, until its rate limit resets. It is safe for concurrent use.
+//
+// Client.WaitForRateLimit should not be used together with it, since
+// Client doesn't know which identity the rate limits belong to.
+type PoolAuthorizer struct {
+	Strategy PoolStrategy
+
+	mu      sync.Mutex
+	members []*poolMember
+	next    int
+}
+
+// NewPoolAuthorizer creates a pool from existing authorizers.
+func NewPoolAuthorizer(auths ...*AnonymousAuthorizer) *PoolAuthorizer {
+	p := &PoolAuthorizer{}
+	for _, a := range auths {
+		p.members = append(p.members, &poolMember{auth: a})
+	}
+	return p
+}
+
+// PoolAuth creates a pool of n new anonymous identities.
+func PoolAuth(ctx context.Context, n int) (*PoolAuthorizer, error) {
+	auths := []*AnonymousAuthorizer{}
+	for i := 0; i < n; i++ {
+		a, err := AnonymousAuth(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("creating identity %d: %w", i, err)
+		}
+		auths = append(auths, a)
+	}
+	return NewPoolAuthorizer(auths...), nil
+}
+
+// pick returns the member to use for the next request. If all of them are
+// throttled, it returns the one that will be available first. Must be
+// called with p.mu held.
+func (p *PoolAuthorizer) pick(now time.Time) *poolMember {
+	var best *poolMember
+	switch p.Strategy {
+	case LeastRecentlyThrottled:
+		// Starting from p.next makes ties, e.g. between identities that
+		// were never throttled, go round-robin.
+		bestIdx := 0
+		for i := range p.members {
+			idx := (p.next + i) % len(p.members)
+			m := p.members[idx]
+			if now.Before(m.throttledUntil) {
+				continue
+			}
+			if best == nil || m.throttledAt.Before(best.throttledAt) {
+				best = m
+				bestIdx = idx
+			}
+		}
+		if best != nil {
+			p.next = (bestIdx + 1) % len(p.members)
+		}
+	default:
+		for i := range p.members {
+			m := p.members[(p.next+i)%len(p.members)]
+			if now.Before(m.throttledUntil) {
+				continue
+			}
+			best = m
+			p.next = (p.next + i + 1) % len(p.members)
+			break
+		}
+	}
+	if best != nil {
+		return best
+	}
+	for _, m := range p.members {
+		if best == nil || m.throttledUntil.Before(best.throttledUntil) {
+			best = m
+		}
+	}
+	return best
+}
+
+func (p *PoolAuthorizer) SetAuthHeader(req *http.Request) {
+	p.mu.Lock()
+	if len(p.members) == 0 {
+		p.mu.Unlock()
+		return
+	}
+	m := p.pick(time.Now())
+	p.mu.Unlock()
+
+	// Remember which identity made the request, since its guest token can
+	// change before the response arrives.
+	*req = *req.WithContext(context.WithValue(req.Context(), poolMemberKey{p}, m))
+	m.auth.SetAuthHeader(req)
+}
+
+type poolMemberKey struct {
+	pool *PoolAuthorizer
+}
+
+type poolAuthorizerJSON struct {
+	Strategy   PoolStrategy           `json:"strategy"`
+	Identities []*AnonymousAuthorizer `json:"identities"`
+}
+
+// MarshalJSON serializes the credentials of all identities in the pool.
+// Throttling state is not saved.
+func (p *PoolAuthorizer) MarshalJSON() ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	v := poolAuthorizerJSON{Strategy: p.Strategy}
+	for _, m := range p.members {
+		v.Identities = append(v.Identities, m.auth)
+	}
+	return json.Marshal(v)
+}
+
+func (p *PoolAuthorizer) UnmarshalJSON(b []byte) error {
+	v := poolAuthorizerJSON{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Strategy = v.Strategy
+	p.members = nil
+	p.next = 0
+	for _, a := range v.Identities {
+		p.members = append(p.members, &poolMember{auth: a})
+	}
+	return nil
+}
+
+// member returns the identity that was used to make the request.
+func (p *PoolAuthorizer) member(req *http.Request) *poolMember {
+	m, _ := req.Context().Value(poolMemberKey{p}).(*poolMember)
+	return m
+}
+
+// ObserveResponse takes the identity that made the request out of rotation
+// if it got throttled, and passes the response on to that identity.
+func (p *PoolAuthorizer) ObserveResponse(resp *http.Response) {
+	if resp.Request == nil {
+		return
+	}
+	m := p.member(resp.Request)
+	if m == nil {
+		return
+	}
+	m.auth.ObserveResponse(resp)
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+	now := time.Now()
+	reset, ok := rateLimitReset(resp.Header)
+	if !ok || reset.Before(now) {
+		reset = now.Add(defaultThrottleDuration)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m.throttledAt = now
+	m.throttledUntil = reset
+}
+
+// invalidateToken passes the invalidation on to the identity that made the
+// request.
+func (p *PoolAuthorizer) invalidateToken(req *http.Request) {
+	if m := p.member(req); m != nil {
+		m.auth.invalidateToken(req)
+	}
+}
@@ -0,0 +1,128 @@
+package pwitter
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func testPool(tokens ...string) *PoolAuthorizer {
+	auths := []*AnonymousAuthorizer{}
+	for _, t := range tokens {
+		auths = append(auths, &AnonymousAuthorizer{info: anonAuthInfo{GuestToken: t}})
+	}
+	return NewPoolAuthorizer(auths...)
+}
+
+func nextToken(t *testing.T, p *PoolAuthorizer) string {
+	t.Helper()
+	req, err := http.NewRequest("GET", "https://twitter.com/", nil)
+	if err != nil {
+		t.Fatalf("creating request: %s", err)
+	}
+	p.SetAuthHeader(req)
+	return req.Header.Get("x-guest-token")
+}
+
+// throttle reports a 429 response to a request made by the identity with
+// the given token.
+func throttle(p *PoolAuthorizer, token string, reset time.Time) {
+	req, _ := http.NewRequest("GET", "https://twitter.com/", nil)
+	for _, m := range p.members {
+		if m.auth.info.GuestToken == token {
+			req = req.WithContext(context.WithValue(req.Context(), poolMemberKey{p}, m))
+		}
+	}
+	req.Header.Set("x-guest-token", token)
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{},
+		Request:    req,
+	}
+	resp.Header.Set("x-rate-limit-reset", strconv.FormatInt(reset.Unix(), 10))
+	p.ObserveResponse(resp)
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	p := testPool("a", "b", "c")
+	got := ""
+	for i := 0; i < 6; i++ {
+		got += nextToken(t, p)
+	}
+	if got != "abcabc" {
+		t.Errorf("unexpected order of identities: %q", got)
+	}
+}
+
+func TestPoolSkipsThrottled(t *testing.T) {
+	p := testPool("a", "b", "c")
+	throttle(p, "b", time.Now().Add(time.Hour))
+	got := ""
+	for i := 0; i < 4; i++ {
+		got += nextToken(t, p)
+	}
+	if got != "acac" {
+		t.Errorf("unexpected order of identities: %q", got)
+	}
+}
+
+func TestPoolAllThrottled(t *testing.T) {
+	p := testPool("a", "b")
+	throttle(p, "a", time.Now().Add(2*time.Hour))
+	throttle(p, "b", time.Now().Add(time.Hour))
+	if got := nextToken(t, p); got != "b" {
+		t.Errorf("expected the identity that resets first, got %q", got)
+	}
+}
+
+func TestPoolLeastRecentlyThrottled(t *testing.T) {
+	p := testPool("a", "b", "c")
+	p.Strategy = LeastRecentlyThrottled
+	now := time.Now()
+	p.members[0].throttledAt = now.Add(-time.Hour)
+	p.members[1].throttledAt = now.Add(-2 * time.Hour)
+	p.members[2].throttledAt = now.Add(-time.Minute)
+	for i := 0; i < 2; i++ {
+		if got := nextToken(t, p); got != "b" {
+			t.Errorf("expected the identity that was throttled the longest time ago, got %q", got)
+		}
+	}
+	throttle(p, "b", now.Add(time.Hour))
+	if got := nextToken(t, p); got != "a" {
+		t.Errorf("expected the next least recently throttled identity, got %q", got)
+	}
+}
+
+func TestPoolLeastRecentlyThrottledTies(t *testing.T) {
+	p := testPool("a", "b", "c")
+	p.Strategy = LeastRecentlyThrottled
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, nextToken(t, p))
+	}
+	if diff := cmp.Diff([]string{"a", "b", "c", "a"}, got); diff != "" {
+		t.Errorf("identities that were never throttled are not used in turn (-want +got):\n%s", diff)
+	}
+}
+
+func TestPoolThrottlesAfterTokenRefresh(t *testing.T) {
+	p := testPool("a", "b")
+	req, _ := http.NewRequest("GET", "https://twitter.com/", nil)
+	p.SetAuthHeader(req)
+	if got := req.Header.Get("x-guest-token"); got != "a" {
+		t.Fatalf("expected the first identity, got %q", got)
+	}
+	// The identity gets a new token while the request is in flight.
+	p.members[0].auth.info.GuestToken = "a2"
+
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}, Request: req}
+	resp.Header.Set("x-rate-limit-reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	p.ObserveResponse(resp)
+	if !time.Now().Before(p.members[0].throttledUntil) {
+		t.Errorf("identity that made the request is not throttled")
+	}
+}
@@ -0,0 +1,108 @@
+package pwitter
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Ukraine-DAO/twitter-threads/twitter"
+	"github.com/rs/zerolog"
+)
+
+// RateLimit is the state of the rate limit bucket of a single GraphQL
+// endpoint, as last reported by the server.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+type rateLimits struct {
+	mu      sync.Mutex
+	buckets map[string]RateLimit
+}
+
+// update records the rate limit headers from the response, if there are any.
+func (l *rateLimits) update(queryName string, h http.Header) {
+	limit, err := strconv.Atoi(h.Get("x-rate-limit-limit"))
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.Atoi(h.Get("x-rate-limit-remaining"))
+	if err != nil {
+		return
+	}
+	reset, ok := rateLimitReset(h)
+	if !ok {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.buckets == nil {
+		l.buckets = map[string]RateLimit{}
+	}
+	l.buckets[queryName] = RateLimit{Limit: limit, Remaining: remaining, Reset: reset}
+}
+
+// reserve takes one request from the bucket and returns zero if it was
+// available, or the time when the bucket resets otherwise.
+func (l *rateLimits) reserve(queryName string) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[queryName]
+	if !ok || time.Now().After(b.Reset) {
+		return time.Time{}
+	}
+	if b.Remaining <= 0 {
+		return b.Reset
+	}
+	b.Remaining--
+	l.buckets[queryName] = b
+	return time.Time{}
+}
+
+func (l *rateLimits) snapshot() map[string]RateLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r := make(map[string]RateLimit, len(l.buckets))
+	for k, v := range l.buckets {
+		r[k] = v
+	}
+	return r
+}
+
+// RateLimits returns the last known rate limits for each GraphQL endpoint,
+// keyed by query name.
+func (c *Client) RateLimits() map[string]RateLimit {
+	return c.rateLimits.snapshot()
+}
+
+// waitForRateLimit blocks until there are requests left in the rate limit
+// bucket for the query. It returns twitter.ErrThrottled right away if the
+// bucket won't be reset before the context deadline.
+func (c *Client) waitForRateLimit(ctx context.Context, queryName string) error {
+	if !c.WaitForRateLimit {
+		return nil
+	}
+	for {
+		reset := c.rateLimits.reserve(queryName)
+		if reset.IsZero() {
+			return nil
+		}
+		if deadline, ok := ctx.Deadline(); ok && deadline.Before(reset) {
+			return twitter.ErrThrottled
+		}
+		zerolog.Ctx(ctx).Debug().Msgf("Rate limit for %s is exhausted, waiting until %s", queryName, reset)
+
+		t := time.NewTimer(time.Until(reset))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
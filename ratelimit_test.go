@@ -0,0 +1,57 @@
+package pwitter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Ukraine-DAO/twitter-threads/twitter"
+)
+
+func TestRateLimitsAreRecorded(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	client, _ := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-rate-limit-limit", "95")
+		w.Header().Set("x-rate-limit-remaining", "94")
+		w.Header().Set("x-rate-limit-reset", strconv.FormatInt(reset.Unix(), 10))
+		fmt.Fprint(w, testUserResponse)
+	})
+
+	if _, err := client.UserByScreenName(context.Background(), "test"); err != nil {
+		t.Fatalf("UserByScreenName returned error: %s", err)
+	}
+	got := client.RateLimits()
+	want := RateLimit{Limit: 95, Remaining: 94, Reset: reset}
+	if len(got) != 1 || got["UserByScreenName"] != want {
+		t.Errorf("unexpected rate limits: %+v", got)
+	}
+}
+
+func TestWaitForRateLimit(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	client, count := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-rate-limit-limit", "95")
+		w.Header().Set("x-rate-limit-remaining", "0")
+		w.Header().Set("x-rate-limit-reset", strconv.FormatInt(reset.Unix(), 10))
+		fmt.Fprint(w, testUserResponse)
+	})
+	client.WaitForRateLimit = true
+
+	if _, err := client.UserByScreenName(context.Background(), "test"); err != nil {
+		t.Fatalf("UserByScreenName returned error: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	_, err := client.UserByScreenName(ctx, "test")
+	if !errors.Is(err, twitter.ErrThrottled) {
+		t.Fatalf("expected ErrThrottled, got %v", err)
+	}
+	if *count != 1 {
+		t.Errorf("expected 1 request, got %d", *count)
+	}
+}
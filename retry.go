@@ -0,0 +1,89 @@
+package pwitter
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy describes how Client retries failed requests. Server errors
+// and network errors are retried with exponential backoff and jitter,
+// throttled requests are retried after the time from x-rate-limit-reset
+// header. Retries never go past the deadline of the request context.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	// MinBackoff is the delay before the first retry. It doubles with each
+	// subsequent attempt, up to MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxRateLimitWait limits how long to wait for a rate limit reset.
+	// Zero means no limit besides the context deadline.
+	MaxRateLimitWait time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:      5,
+	MinBackoff:       time.Second,
+	MaxBackoff:       time.Minute,
+	MaxRateLimitWait: 15 * time.Minute,
+}
+
+// delay returns how long to wait before the next attempt, or false if the
+// request should not be retried. resp is nil if the request failed
+// without getting a response.
+func (p *RetryPolicy) delay(attempt int, resp *http.Response) (time.Duration, bool) {
+	if p == nil || attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	switch {
+	case resp == nil:
+		return p.backoff(attempt), true
+	case resp.StatusCode == http.StatusTooManyRequests:
+		reset, ok := rateLimitReset(resp.Header)
+		if !ok {
+			return p.backoff(attempt), true
+		}
+		d := time.Until(reset)
+		if d < 0 {
+			d = 0
+		}
+		if p.MaxRateLimitWait > 0 && d > p.MaxRateLimitWait {
+			return 0, false
+		}
+		return d, true
+	case resp.StatusCode >= 500:
+		return p.backoff(attempt), true
+	}
+	return 0, false
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MinBackoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	// "Equal jitter": keep at least half of the delay.
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// rateLimitReset parses x-rate-limit-reset header, which contains a Unix
+// timestamp in seconds.
+func rateLimitReset(h http.Header) (time.Time, bool) {
+	v := h.Get("x-rate-limit-reset")
+	if v == "" {
+		return time.Time{}, false
+	}
+	ts, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(ts, 0), true
+}
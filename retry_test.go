@@ -0,0 +1,179 @@
+package pwitter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Ukraine-DAO/twitter-threads/twitter"
+)
+
+const testUserResponse = `{"data":{"user":{"result":{"__typename":"User","rest_id":"42","legacy":{"name":"Test","screen_name":"test"}}}}}`
+
+type nopAuthorizer struct{}
+
+func (nopAuthorizer) SetAuthHeader(req *http.Request) {}
+
+// redirectTransport sends all requests to the test server, regardless of
+// the host in the URL.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// newTestClient returns a Client that talks to a test server with the given
+// handler, and a counter of the requests that server received.
+func newTestClient(t *testing.T, handler func(n int, w http.ResponseWriter, r *http.Request)) (*Client, *int32) {
+	t.Helper()
+	var count int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(int(atomic.AddInt32(&count, 1)), w, r)
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	return &Client{
+		Authorizer: nopAuthorizer{},
+		Client:     &http.Client{Transport: &redirectTransport{target: u}},
+	}, &count
+}
+
+var testRetryPolicy = &RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  time.Millisecond,
+	MaxBackoff:  10 * time.Millisecond,
+}
+
+func TestRetryServerError(t *testing.T) {
+	client, count := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		if n < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, testUserResponse)
+	})
+	client.Retry = testRetryPolicy
+
+	r, err := client.UserByScreenName(context.Background(), "test")
+	if err != nil {
+		t.Fatalf("UserByScreenName returned error: %s", err)
+	}
+	if r.ID != "42" {
+		t.Errorf("unexpected user ID %q", r.ID)
+	}
+	if *count != 3 {
+		t.Errorf("expected 3 requests, got %d", *count)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	client, count := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	client.Retry = testRetryPolicy
+
+	if _, err := client.UserByScreenName(context.Background(), "test"); err == nil {
+		t.Fatalf("UserByScreenName succeeded unexpectedly")
+	}
+	if *count != 3 {
+		t.Errorf("expected 3 requests, got %d", *count)
+	}
+}
+
+func TestNoRetryWithoutPolicy(t *testing.T) {
+	client, count := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	if _, err := client.UserByScreenName(context.Background(), "test"); err == nil {
+		t.Fatalf("UserByScreenName succeeded unexpectedly")
+	}
+	if *count != 1 {
+		t.Errorf("expected 1 request, got %d", *count)
+	}
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	client, count := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	client.Retry = testRetryPolicy
+
+	if _, err := client.UserByScreenName(context.Background(), "test"); err == nil {
+		t.Fatalf("UserByScreenName succeeded unexpectedly")
+	}
+	if *count != 1 {
+		t.Errorf("expected 1 request, got %d", *count)
+	}
+}
+
+func TestRetryWaitsForRateLimitReset(t *testing.T) {
+	reset := time.Now().Add(time.Second).Truncate(time.Second).Add(time.Second)
+	client, count := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		if n == 1 {
+			w.Header().Set("x-rate-limit-reset", strconv.FormatInt(reset.Unix(), 10))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, testUserResponse)
+	})
+	client.Retry = testRetryPolicy
+
+	if _, err := client.UserByScreenName(context.Background(), "test"); err != nil {
+		t.Fatalf("UserByScreenName returned error: %s", err)
+	}
+	if time.Now().Before(reset) {
+		t.Errorf("request was retried before the rate limit reset")
+	}
+	if *count != 2 {
+		t.Errorf("expected 2 requests, got %d", *count)
+	}
+}
+
+func TestRetryRespectsDeadline(t *testing.T) {
+	client, count := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-rate-limit-reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	client.Retry = &RetryPolicy{MaxAttempts: 3}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	start := time.Now()
+	_, err := client.UserByScreenName(ctx, "test")
+	if !errors.Is(err, twitter.ErrThrottled) {
+		t.Fatalf("expected ErrThrottled, got %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Errorf("waited for the rate limit reset despite the deadline")
+	}
+	if *count != 1 {
+		t.Errorf("expected 1 request, got %d", *count)
+	}
+}
+
+func TestBackoffBounds(t *testing.T) {
+	p := &RetryPolicy{MaxAttempts: 10, MinBackoff: time.Second, MaxBackoff: 8 * time.Second}
+	for attempt := 1; attempt < 10; attempt++ {
+		want := time.Second << (attempt - 1)
+		if want > p.MaxBackoff {
+			want = p.MaxBackoff
+		}
+		d := p.backoff(attempt)
+		if d < want/2 || d > want {
+			t.Errorf("backoff(%d) = %s, want between %s and %s", attempt, d, want/2, want)
+		}
+	}
+}
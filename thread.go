@@ -0,0 +1,159 @@
+package pwitter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Ukraine-DAO/twitter-threads/twitter"
+	"github.com/rs/zerolog"
+)
+
+type AuthorThreadResponse struct {
+	// Tweets are the tweets of the thread, starting with the root.
+	Tweets []Tweet
+}
+
+// Twitter converts the thread for use with twitter-threads.
+func (r *AuthorThreadResponse) Twitter() []twitter.Tweet {
+	var tweets []twitter.Tweet
+	for _, tw := range r.Tweets {
+		tweets = append(tweets, tw.Twitter())
+	}
+	return tweets
+}
+
+// AuthorThread unrolls a thread that the author posted as a chain of replies
+// to themselves. tweetID can be any tweet of the conversation. Replies by
+// other users are skipped.
+func (c *Client) AuthorThread(ctx context.Context, tweetID string) (*AuthorThreadResponse, error) {
+	log := zerolog.Ctx(ctx).With().
+		Str("tweet_id", tweetID).
+		Str("method", "AuthorThread").Logger()
+	ctx = log.WithContext(ctx)
+
+	conv, err := c.authorThreadPage(ctx, tweetID)
+	if err != nil {
+		return nil, err
+	}
+	root := conv.Root.Tweet
+	if root.Tombstone != nil || root.AuthorID == "" {
+		return nil, fmt.Errorf("the first tweet of the conversation (%s) is not available", root.ID)
+	}
+
+	r := &AuthorThreadResponse{}
+	seen := map[string]bool{}
+	n := conv.Root
+	for n != nil {
+		for ; n != nil && !seen[n.Tweet.ID]; n = nextInThread(n, &root) {
+			r.Tweets = append(r.Tweets, n.Tweet)
+			seen[n.Tweet.ID] = true
+		}
+
+		// The requested tweet might be in the middle of the thread, or
+		// the chain can be broken into several modules, so we continue
+		// from the last tweet we've got, unless we've already fetched
+		// its replies.
+		last := r.Tweets[len(r.Tweets)-1].ID
+		if last == tweetID {
+			break
+		}
+		more, err := c.authorThreadPage(ctx, last)
+		if err != nil {
+			log.Info().Err(err).Msgf("Failed to fetch the continuation of the thread after %s: %s", last, err)
+			break
+		}
+		n = nextInThread(more.Focal, &root)
+		tweetID = last
+	}
+
+	for i := 1; i < len(r.Tweets); i++ {
+		addIncludedTweet(&r.Tweets[i], r.Tweets[i-1].TweetNoIncludes)
+	}
+	return r, nil
+}
+
+// authorThreadPage fetches a tweet with the tweets it replies to and the
+// first replies to it. Unlike Conversation, it follows only the "show more"
+// cursors of the chain of replies by the author of the tweet, so that
+// unrolling a long thread doesn't page through all the other replies.
+func (c *Client) authorThreadPage(ctx context.Context, tweetID string) (*ConversationResponse, error) {
+	log := zerolog.Ctx(ctx)
+
+	b := newConversationBuilder(tweetID)
+	if err := c.addConversationPage(ctx, b, ""); err != nil {
+		return nil, err
+	}
+	followed := map[string]bool{}
+	for {
+		module := b.selfReplyModule()
+		cursor := b.moduleCursors[module]
+		if cursor == "" || followed[cursor] {
+			break
+		}
+		followed[cursor] = true
+		if err := c.addConversationPage(ctx, b, cursor); err != nil {
+			log.Info().Err(err).Msgf("Failed to fetch more of the thread: %s", err)
+			break
+		}
+	}
+
+	return c.finishConversation(ctx, b), nil
+}
+
+// selfReplyModule returns the ID of the module that starts with a reply to
+// the focal tweet by its author, if there is one.
+func (b *conversationBuilder) selfReplyModule() string {
+	focal := b.nodes[b.focalID]
+	if focal == nil || focal.Tweet.AuthorID == "" {
+		return ""
+	}
+	for _, m := range b.modules {
+		first := b.nodes[b.firstInModule[m]]
+		if first == nil {
+			continue
+		}
+		if first.Tweet.AuthorID == focal.Tweet.AuthorID && first.Tweet.InReplyTo() == b.focalID {
+			return m
+		}
+	}
+	return ""
+}
+
+// nextInThread returns the earliest reply to n by the author of the root
+// tweet.
+func nextInThread(n *ConversationNode, root *Tweet) *ConversationNode {
+	var next *ConversationNode
+	for _, c := range n.Replies {
+		tw := &c.Tweet
+		if tw.AuthorID != root.AuthorID {
+			continue
+		}
+		if tw.ConversationID != "" && root.ConversationID != "" && tw.ConversationID != root.ConversationID {
+			continue
+		}
+		if next == nil || olderTweetID(tw.ID, next.Tweet.ID) {
+			next = c
+		}
+	}
+	return next
+}
+
+// olderTweetID reports whether tweet a was posted before tweet b. Tweet IDs
+// are increasing numbers of varying length.
+func olderTweetID(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// addIncludedTweet adds a referenced tweet to includes, unless it's already
+// there.
+func addIncludedTweet(tw *Tweet, ref TweetNoIncludes) {
+	for _, t := range tw.Includes.Tweets {
+		if t.ID == ref.ID {
+			return
+		}
+	}
+	tw.Includes.Tweets = append(tw.Includes.Tweets, ref)
+}
@@ -0,0 +1,126 @@
+package pwitter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAuthorThread(t *testing.T) {
+	const author, other = "783214", "2244994945"
+	pages := map[string]string{
+		"1": testTweetDetailEntries(
+			testTimelineItem("tweet-1", testTimelineTweetBy("1", "", author)),
+			testModule("conversationthread-2",
+				testModuleItem("conversationthread-2-tweet-2", testTimelineTweetBy("2", "1", author)),
+				testModuleItem("conversationthread-2-tweet-3", testTimelineTweetBy("3", "2", author))),
+			testModule("conversationthread-10",
+				testModuleItem("conversationthread-10-tweet-10", testTimelineTweetBy("10", "1", other))),
+		),
+		"3": testTweetDetailEntries(
+			testTimelineItem("tweet-1", testTimelineTweetBy("1", "", author)),
+			testTimelineItem("tweet-2", testTimelineTweetBy("2", "1", author)),
+			testTimelineItem("tweet-3", testTimelineTweetBy("3", "2", author)),
+			testModule("conversationthread-11",
+				testModuleItem("conversationthread-11-tweet-11", testTimelineTweetBy("11", "3", other))),
+			testModule("conversationthread-4",
+				testModuleItem("conversationthread-4-tweet-4", testTimelineTweetBy("4", "3", author))),
+		),
+		"4": testTweetDetailEntries(
+			testTimelineItem("tweet-1", testTimelineTweetBy("1", "", author)),
+			testTimelineItem("tweet-2", testTimelineTweetBy("2", "1", author)),
+			testTimelineItem("tweet-3", testTimelineTweetBy("3", "2", author)),
+			testTimelineItem("tweet-4", testTimelineTweetBy("4", "3", author)),
+		),
+	}
+	client, count := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		resp, ok := pages[tweetDetailRequestVars(t, r).ID]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, resp)
+	})
+
+	r, err := client.AuthorThread(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("AuthorThread returned error: %s", err)
+	}
+	var got []string
+	for _, tw := range r.Twitter() {
+		got = append(got, tw.ID)
+		if tw.AuthorID != author {
+			t.Errorf("tweet %s is by %s, want %s", tw.ID, tw.AuthorID, author)
+		}
+		if replyTo := tw.InReplyTo(); replyTo != "" && tw.Includes.Tweets == nil {
+			t.Errorf("tweet %s doesn't include the tweet it replies to", tw.ID)
+		}
+	}
+	if diff := cmp.Diff([]string{"1", "2", "3", "4"}, got); diff != "" {
+		t.Errorf("unexpected thread (-want +got):\n%s", diff)
+	}
+	if *count != 3 {
+		t.Errorf("expected 3 requests, got %d", *count)
+	}
+}
+
+func TestAuthorThreadFollowsOnlySelfReplies(t *testing.T) {
+	const author, other = "783214", "2244994945"
+	pages := map[string]string{
+		"1/": testTweetDetailEntries(
+			testTimelineItem("tweet-1", testTimelineTweetBy("1", "", author)),
+			testModule("conversationthread-10",
+				testModuleItem("conversationthread-10-tweet-10", testTimelineTweetBy("10", "1", other)),
+				testModuleItem("conversationthread-10-cursor-showmore-1", testTimelineCursor("ShowMore", "other"))),
+			testModule("conversationthread-2",
+				testModuleItem("conversationthread-2-tweet-2", testTimelineTweetBy("2", "1", author)),
+				testModuleItem("conversationthread-2-cursor-showmore-1", testTimelineCursor("ShowMore", "more"))),
+			testTimelineItem("cursor-bottom-1", testTimelineCursor("Bottom", "bottom")),
+		),
+		"1/more": testTweetDetailInstructions(testAddToModule("conversationthread-2",
+			testModuleItem("conversationthread-2-tweet-3", testTimelineTweetBy("3", "2", author)),
+			testModuleItem("conversationthread-2-cursor-showmore-2", testTimelineCursor("ShowMore", "more2")))),
+		"1/more2": testTweetDetailInstructions(testAddToModule("conversationthread-2",
+			testModuleItem("conversationthread-2-tweet-4", testTimelineTweetBy("4", "3", author)))),
+		"4/": testTweetDetailEntries(
+			testTimelineItem("tweet-1", testTimelineTweetBy("1", "", author)),
+			testTimelineItem("tweet-2", testTimelineTweetBy("2", "1", author)),
+			testTimelineItem("tweet-3", testTimelineTweetBy("3", "2", author)),
+			testTimelineItem("tweet-4", testTimelineTweetBy("4", "3", author)),
+			testModule("conversationthread-11",
+				testModuleItem("conversationthread-11-tweet-11", testTimelineTweetBy("11", "4", other)),
+				testModuleItem("conversationthread-11-cursor-showmore-1", testTimelineCursor("ShowMore", "other"))),
+			testTimelineItem("cursor-bottom-1", testTimelineCursor("Bottom", "bottom")),
+		),
+	}
+	var requested []string
+	client, _ := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		vars := tweetDetailRequestVars(t, r)
+		key := vars.ID + "/" + vars.Cursor
+		requested = append(requested, key)
+		resp, ok := pages[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, resp)
+	})
+
+	r, err := client.AuthorThread(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("AuthorThread returned error: %s", err)
+	}
+	var got []string
+	for _, tw := range r.Tweets {
+		got = append(got, tw.ID)
+	}
+	if diff := cmp.Diff([]string{"1", "2", "3", "4"}, got); diff != "" {
+		t.Errorf("unexpected thread (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"1/", "1/more", "1/more2", "4/"}, requested); diff != "" {
+		t.Errorf("unexpected requests (-want +got):\n%s", diff)
+	}
+}
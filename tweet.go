@@ -0,0 +1,220 @@
+package pwitter
+
+import (
+	"github.com/Ukraine-DAO/twitter-threads/twitter"
+)
+
+// Tweet is a tweet in the format of API v2 response, together with the
+// objects it references. It carries more data than twitter.Tweet, use
+// Twitter() to get a value that twitter-threads tooling can consume.
+type Tweet struct {
+	TweetNoIncludes
+	Includes TweetIncludes `json:"includes,omitempty"`
+}
+
+type TweetNoIncludes struct {
+	ID               string                    `json:"id"`
+	Text             string                    `json:"text"`
+	ConversationID   string                    `json:"conversation_id"`
+	AuthorID         string                    `json:"author_id"`
+	ReferencedTweets []twitter.ReferencedTweet `json:"referenced_tweets,omitempty"`
+	Entities         Entities                  `json:"entities,omitempty"`
+	Attachments      Attachments               `json:"attachments,omitempty"`
+	CreatedAt        string                    `json:"created_at,omitempty"`
+	InReplyToUserID  string                    `json:"in_reply_to_user_id,omitempty"`
+	PublicMetrics    *TweetMetrics             `json:"public_metrics,omitempty"`
+	// EditHistoryTweetIDs are IDs of all versions of the tweet, oldest
+	// first.
+	EditHistoryTweetIDs []string      `json:"edit_history_tweet_ids,omitempty"`
+	EditControls        *EditControls `json:"edit_controls,omitempty"`
+	// CommunityNote is the note shown under the tweet, if any. Use
+	// WithCommunityNotes to request it with TweetDetail.
+	CommunityNote *CommunityNote `json:"community_note,omitempty"`
+	// Card is the link preview shown with the tweet.
+	Card *Card `json:"card,omitempty"`
+	// RichText is the formatting of long-form tweets.
+	RichText []RichTextTag `json:"richtext_tags,omitempty"`
+	// HTMLUnescaped is set if HTML entities in Text were replaced with the
+	// characters they stand for, see Client.UnescapeHTML. Entity offsets
+	// always point into Text as it is.
+	HTMLUnescaped bool `json:"html_unescaped,omitempty"`
+	// Tombstone is set if the tweet can't be shown, in which case only
+	// the ID is known.
+	Tombstone *Tombstone `json:"tombstone,omitempty"`
+}
+
+// Entities are the parts of tweet text that have special meaning, in the
+// format of API v2 entities object.
+type Entities struct {
+	URLs        []twitter.EntityURL     `json:"urls,omitempty"`
+	Hashtags    []twitter.EntityHashtag `json:"hashtags,omitempty"`
+	Mentions    []twitter.EntityMention `json:"mentions,omitempty"`
+	Cashtags    []EntityCashtag         `json:"cashtags,omitempty"`
+	Annotations []EntityAnnotation      `json:"annotations,omitempty"`
+	// Timestamps are links to a moment in the attached video.
+	Timestamps []EntityTimestamp `json:"timestamps,omitempty"`
+}
+
+type EntityCashtag struct {
+	twitter.TextEntity
+	Tag string `json:"tag,omitempty"`
+}
+
+type EntityAnnotation struct {
+	twitter.TextEntity
+	Probability    float64 `json:"probability"`
+	Type           string  `json:"type"`
+	NormalizedText string  `json:"normalized_text"`
+}
+
+type EntityTimestamp struct {
+	twitter.TextEntity
+	Seconds int    `json:"seconds"`
+	Text    string `json:"text,omitempty"`
+}
+
+// Twitter converts the entities for use with twitter-threads, which
+// doesn't know about cashtags, annotations and timestamps.
+func (e *Entities) Twitter() twitter.Entities {
+	return twitter.Entities{
+		URLs:     e.URLs,
+		Hashtags: e.Hashtags,
+		Mentions: e.Mentions,
+	}
+}
+
+type EditControls struct {
+	EditsRemaining int    `json:"edits_remaining"`
+	IsEditEligible bool   `json:"is_edit_eligible"`
+	EditableUntil  string `json:"editable_until,omitempty"`
+}
+
+type Attachments struct {
+	MediaKeys []string `json:"media_keys,omitempty"`
+	PollIDs   []string `json:"poll_ids,omitempty"`
+}
+
+// Tombstone explains why a tweet is not available.
+type Tombstone struct {
+	// Reason is a short machine-readable reason, e.g. "Protected".
+	Reason string `json:"reason,omitempty"`
+	// Text is the message shown instead of the tweet.
+	Text string `json:"text,omitempty"`
+}
+
+func tombstoneTweet(id string, t *Tombstone) Tweet {
+	return Tweet{TweetNoIncludes: TweetNoIncludes{ID: id, Tombstone: t}}
+}
+
+// RichTextTag applies formatting (e.g. "Bold" or "Italic") to a part of the
+// text.
+type RichTextTag struct {
+	twitter.TextEntity
+	Types []string `json:"types"`
+}
+
+// TweetMetrics are engagement counters of a tweet.
+type TweetMetrics struct {
+	RetweetCount    int `json:"retweet_count"`
+	ReplyCount      int `json:"reply_count"`
+	LikeCount       int `json:"like_count"`
+	QuoteCount      int `json:"quote_count"`
+	BookmarkCount   int `json:"bookmark_count"`
+	ImpressionCount int `json:"impression_count"`
+}
+
+// Media is a photo, video or GIF attached to a tweet, in the format of API v2
+// media object.
+type Media struct {
+	twitter.Media
+	Width         int           `json:"width,omitempty"`
+	Height        int           `json:"height,omitempty"`
+	DurationMS    int           `json:"duration_ms,omitempty"`
+	PublicMetrics *MediaMetrics `json:"public_metrics,omitempty"`
+	// Availability tells if the media can still be viewed, e.g. it's
+	// unavailable if it was taken down because of a copyright claim.
+	Availability *MediaAvailability `json:"availability,omitempty"`
+}
+
+type MediaMetrics struct {
+	ViewCount int `json:"view_count"`
+}
+
+type MediaAvailability struct {
+	// Status is either "Available" or "Unavailable".
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type TweetIncludes struct {
+	Users  []User            `json:"users,omitempty"`
+	Media  []Media           `json:"media,omitempty"`
+	Tweets []TweetNoIncludes `json:"tweets,omitempty"`
+	Polls  []Poll            `json:"polls,omitempty"`
+}
+
+func (t *Tweet) unescapeHTML() {
+	t.TweetNoIncludes.unescapeHTML()
+	for i := range t.Includes.Tweets {
+		t.Includes.Tweets[i].unescapeHTML()
+	}
+}
+
+// unescapeHTML replaces HTML entities in the text and moves entities so
+// that they point into the unescaped text.
+func (t *TweetNoIncludes) unescapeHTML() {
+	if t.HTMLUnescaped {
+		return
+	}
+	o := unescapeOffsets(t.Text)
+	t.Text = unescapeHTML(t.Text)
+	t.Entities = t.Entities.remap(o)
+	t.RichText = remapRichText(t.RichText, o)
+	t.HTMLUnescaped = true
+}
+
+// InReplyTo returns the ID of the tweet this one replies to, if any.
+func (t *TweetNoIncludes) InReplyTo() string {
+	for _, ref := range t.ReferencedTweets {
+		if ref.Type == "replied_to" {
+			return ref.ID
+		}
+	}
+	return ""
+}
+
+// Twitter converts the tweet for use with twitter-threads.
+// twitter-threads expects HTML-escaped text, same as API v2 returns.
+func (t *TweetNoIncludes) Twitter() twitter.TweetNoIncludes {
+	text, entities := t.Text, t.Entities
+	if t.HTMLUnescaped {
+		entities = entities.remap(escapeOffsets(text))
+		text = escapeHTML(text)
+	}
+	return twitter.TweetNoIncludes{
+		ID:               t.ID,
+		Text:             text,
+		ConversationID:   t.ConversationID,
+		AuthorID:         t.AuthorID,
+		ReferencedTweets: t.ReferencedTweets,
+		Entities:         entities.Twitter(),
+		Attachments:      twitter.Attachments{MediaKeys: t.Attachments.MediaKeys},
+		CreatedAt:        t.CreatedAt,
+		InReplyToUserID:  t.InReplyToUserID,
+	}
+}
+
+// Twitter converts the tweet and its includes for use with twitter-threads.
+func (t *Tweet) Twitter() twitter.Tweet {
+	r := twitter.Tweet{TweetNoIncludes: t.TweetNoIncludes.Twitter()}
+	for _, u := range t.Includes.Users {
+		r.Includes.Users = append(r.Includes.Users, u.TwitterUser())
+	}
+	for _, m := range t.Includes.Media {
+		r.Includes.Media = append(r.Includes.Media, m.Media)
+	}
+	for _, tw := range t.Includes.Tweets {
+		r.Includes.Tweets = append(r.Includes.Tweets, tw.Twitter())
+	}
+	return r
+}
@@ -0,0 +1,129 @@
+package pwitter
+
+import (
+	"github.com/Ukraine-DAO/twitter-threads/twitter"
+)
+
+// User is a user profile, in the format of API v2 user object with a few
+// extra fields that are only available through GraphQL API.
+type User struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	Username         string       `json:"username"`
+	Description      string       `json:"description,omitempty"`
+	Location         string       `json:"location,omitempty"`
+	URL              string       `json:"url,omitempty"`
+	Entities         UserEntities `json:"entities,omitempty"`
+	PublicMetrics    UserMetrics  `json:"public_metrics"`
+	CreatedAt        string       `json:"created_at,omitempty"`
+	Verified         bool         `json:"verified"`
+	VerifiedType     string       `json:"verified_type,omitempty"`
+	IsBlueVerified   bool         `json:"is_blue_verified"`
+	Protected        bool         `json:"protected"`
+	ProfileImageURL  string       `json:"profile_image_url,omitempty"`
+	ProfileBannerURL string       `json:"profile_banner_url,omitempty"`
+
+	Professional *UserProfessional `json:"professional,omitempty"`
+	Affiliation  *UserAffiliation  `json:"affiliation,omitempty"`
+}
+
+type UserEntities struct {
+	URL         *UserEntityURLs `json:"url,omitempty"`
+	Description *UserEntityURLs `json:"description,omitempty"`
+}
+
+type UserEntityURLs struct {
+	URLs []twitter.EntityURL `json:"urls,omitempty"`
+}
+
+type UserMetrics struct {
+	FollowersCount int `json:"followers_count"`
+	FollowingCount int `json:"following_count"`
+	TweetCount     int `json:"tweet_count"`
+	ListedCount    int `json:"listed_count"`
+}
+
+func convertUserEntityURLs(urls []entityURL) *UserEntityURLs {
+	if len(urls) == 0 {
+		return nil
+	}
+	r := &UserEntityURLs{}
+	for _, ue := range urls {
+		r.URLs = append(r.URLs, twitter.EntityURL{
+			TextEntity:  textEntity(ue.Indices),
+			URL:         ue.URL,
+			ExpandedURL: ue.ExpandedURL,
+			DisplayURL:  ue.DisplayURL,
+		})
+	}
+	return r
+}
+
+func (u *graphqlUser) User() User {
+	r := User{
+		ID:             u.RestID,
+		IsBlueVerified: u.IsBlueVerified,
+	}
+	if p := u.Professional; p != nil {
+		r.Professional = &UserProfessional{Type: p.ProfessionalType}
+		for _, c := range p.Category {
+			r.Professional.Categories = append(r.Professional.Categories, c.Name)
+		}
+	}
+	if l := u.AffiliatesHighlightedLabel.Label; l != nil {
+		r.Affiliation = &UserAffiliation{
+			Description: l.Description,
+			URL:         l.URL.URL,
+			BadgeURL:    l.Badge.URL,
+			LabelType:   l.UserLabelType,
+		}
+	}
+	if u.Legacy == nil {
+		return r
+	}
+	l := u.Legacy
+	r.Name = l.Name
+	r.Username = l.ScreenName
+	r.Description = l.Description
+	r.Location = l.Location
+	r.URL = l.URL
+	r.Entities.URL = convertUserEntityURLs(l.Entities.URL.URLs)
+	r.Entities.Description = convertUserEntityURLs(l.Entities.Description.URLs)
+	r.PublicMetrics = UserMetrics{
+		FollowersCount: l.FollowersCount,
+		FollowingCount: l.FriendsCount,
+		TweetCount:     l.StatusesCount,
+		ListedCount:    l.ListedCount,
+	}
+	r.CreatedAt = convertTimestamp(l.CreatedAt)
+	r.Verified = l.Verified
+	r.VerifiedType = l.VerifiedType
+	r.Protected = l.Protected
+	r.ProfileImageURL = l.ProfileImageURLHTTPS
+	r.ProfileBannerURL = l.ProfileBannerURL
+	return r
+}
+
+// UserProfessional describes a professional (creator or business) account.
+type UserProfessional struct {
+	Type       string   `json:"type"`
+	Categories []string `json:"categories,omitempty"`
+}
+
+// UserAffiliation is the label shown next to the name of users affiliated
+// with an organization.
+type UserAffiliation struct {
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url,omitempty"`
+	BadgeURL    string `json:"badge_url,omitempty"`
+	LabelType   string `json:"label_type,omitempty"`
+}
+
+// TwitterUser converts the user for use with twitter-threads.
+func (u *User) TwitterUser() twitter.TwitterUser {
+	return twitter.TwitterUser{
+		ID:       u.ID,
+		Name:     u.Name,
+		Username: u.Username,
+	}
+}
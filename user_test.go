@@ -0,0 +1,206 @@
+package pwitter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Ukraine-DAO/twitter-threads/twitter"
+	"github.com/google/go-cmp/cmp"
+)
+
+func parseTestUser(t *testing.T, s string) User {
+	t.Helper()
+	o := &graphqlObject{}
+	if err := json.Unmarshal([]byte(s), o); err != nil {
+		t.Fatalf("unmarshaling test user: %s", err)
+	}
+	v, err := o.Parse()
+	if err != nil {
+		t.Fatalf("parsing test user: %s", err)
+	}
+	u, ok := v.(*graphqlUser)
+	if !ok {
+		t.Fatalf("test user has unexpected type %T", v)
+	}
+	return u.User()
+}
+
+func TestUser(t *testing.T) {
+	cases := []struct {
+		desc string
+		json string
+		want User
+	}{
+		{
+			desc: "regular user",
+			json: `{
+				"__typename": "User",
+				"rest_id": "2244994945",
+				"is_blue_verified": false,
+				"legacy": {
+					"name": "Twitter Dev",
+					"screen_name": "TwitterDev",
+					"description": "The voice of the #TwitterDev team. See https://t.co/dev",
+					"location": "127.0.0.1",
+					"url": "https://t.co/home",
+					"created_at": "Sat Dec 14 04:35:55 +0000 2013",
+					"followers_count": 570000,
+					"friends_count": 2000,
+					"statuses_count": 3900,
+					"listed_count": 1800,
+					"protected": false,
+					"profile_image_url_https": "https://pbs.twimg.com/profile_images/1/a_normal.jpg",
+					"profile_banner_url": "https://pbs.twimg.com/profile_banners/2244994945/1",
+					"entities": {
+						"url": {"urls": [{"indices": [0, 17], "url": "https://t.co/home", "expanded_url": "https://developer.twitter.com", "display_url": "developer.twitter.com"}]},
+						"description": {"urls": [{"indices": [38, 54], "url": "https://t.co/dev", "expanded_url": "https://dev.to", "display_url": "dev.to"}]}
+					}
+				}
+			}`,
+			want: User{
+				ID:          "2244994945",
+				Name:        "Twitter Dev",
+				Username:    "TwitterDev",
+				Description: "The voice of the #TwitterDev team. See https://t.co/dev",
+				Location:    "127.0.0.1",
+				URL:         "https://t.co/home",
+				Entities: UserEntities{
+					URL: &UserEntityURLs{URLs: []twitter.EntityURL{{
+						TextEntity: twitter.TextEntity{Start: 0, End: 17}, URL: "https://t.co/home",
+						ExpandedURL: "https://developer.twitter.com", DisplayURL: "developer.twitter.com",
+					}}},
+					Description: &UserEntityURLs{URLs: []twitter.EntityURL{{
+						TextEntity: twitter.TextEntity{Start: 38, End: 54}, URL: "https://t.co/dev",
+						ExpandedURL: "https://dev.to", DisplayURL: "dev.to",
+					}}},
+				},
+				PublicMetrics: UserMetrics{
+					FollowersCount: 570000,
+					FollowingCount: 2000,
+					TweetCount:     3900,
+					ListedCount:    1800,
+				},
+				CreatedAt:        "2013-12-14T04:35:55.000Z",
+				ProfileImageURL:  "https://pbs.twimg.com/profile_images/1/a_normal.jpg",
+				ProfileBannerURL: "https://pbs.twimg.com/profile_banners/2244994945/1",
+			},
+		},
+		{
+			desc: "professional business account with affiliation",
+			json: `{
+				"__typename": "User",
+				"rest_id": "783214",
+				"is_blue_verified": true,
+				"professional": {
+					"rest_id": "1",
+					"professional_type": "Business",
+					"category": [{"id": 477, "name": "Technology Company"}]
+				},
+				"affiliates_highlighted_label": {
+					"label": {
+						"description": "X Corp",
+						"url": {"url": "https://twitter.com/XCorp", "urlType": "DeepLink"},
+						"badge": {"url": "https://pbs.twimg.com/profile_images/2/b_bigger.jpg"},
+						"userLabelType": "BusinessLabel"
+					}
+				},
+				"legacy": {
+					"name": "Twitter",
+					"screen_name": "Twitter",
+					"verified": true,
+					"verified_type": "Business"
+				}
+			}`,
+			want: User{
+				ID:             "783214",
+				Name:           "Twitter",
+				Username:       "Twitter",
+				Verified:       true,
+				VerifiedType:   "Business",
+				IsBlueVerified: true,
+				Professional: &UserProfessional{
+					Type:       "Business",
+					Categories: []string{"Technology Company"},
+				},
+				Affiliation: &UserAffiliation{
+					Description: "X Corp",
+					URL:         "https://twitter.com/XCorp",
+					BadgeURL:    "https://pbs.twimg.com/profile_images/2/b_bigger.jpg",
+					LabelType:   "BusinessLabel",
+				},
+			},
+		},
+		{
+			desc: "government account",
+			json: `{
+				"__typename": "User",
+				"rest_id": "9",
+				"legacy": {"name": "Gov", "screen_name": "gov", "verified_type": "Government", "protected": true}
+			}`,
+			want: User{
+				ID:           "9",
+				Name:         "Gov",
+				Username:     "gov",
+				VerifiedType: "Government",
+				Protected:    true,
+			},
+		},
+		{
+			desc: "no legacy data",
+			json: `{"__typename": "User", "rest_id": "10", "is_blue_verified": true}`,
+			want: User{ID: "10", IsBlueVerified: true},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := parseTestUser(t, tc.json)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected user (-want +got):\n%s", diff)
+			}
+			want := twitter.TwitterUser{ID: tc.want.ID, Name: tc.want.Name, Username: tc.want.Username}
+			if diff := cmp.Diff(want, got.TwitterUser()); diff != "" {
+				t.Errorf("unexpected twitter user (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUserByRestID(t *testing.T) {
+	responses := map[string]string{
+		"42": testUserResponse,
+		"63": `{"data":{"user":{"result":{"__typename":"UserUnavailable","reason":"Suspended"}}}}`,
+		"50": `{"data":{"user":{}},"errors":[{"code":50,"message":"User not found."}]}`,
+	}
+	client, _ := newTestClient(t, func(n int, w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/UserByRestId") {
+			t.Errorf("unexpected request path %q", r.URL.Path)
+		}
+		vars := userByRestIDVariables{}
+		if err := json.Unmarshal([]byte(r.URL.Query().Get("variables")), &vars); err != nil {
+			t.Errorf("unmarshaling variables: %s", err)
+		}
+		fmt.Fprint(w, responses[vars.UserID])
+	})
+
+	r, err := client.UserByRestID(context.Background(), "42")
+	if err != nil {
+		t.Fatalf("UserByRestID returned error: %s", err)
+	}
+	if r.User.ID != "42" || r.User.Username != "test" || r.User.Name != "Test" {
+		t.Errorf("unexpected user: %+v", r.User)
+	}
+	if len(r.RawJSON) == 0 {
+		t.Errorf("RawJSON is empty")
+	}
+
+	for id, want := range map[string]error{"63": ErrUserSuspended, "50": ErrUserNotFound} {
+		if _, err := client.UserByRestID(context.Background(), id); !errors.Is(err, want) {
+			t.Errorf("UserByRestID(%q) returned error %v, want %v", id, err, want)
+		}
+	}
+}